@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/bensiauu/financial-assistance-scheme/internal/middleware"
 	"github.com/bensiauu/financial-assistance-scheme/internal/router"
+	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
 	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
 )
 
@@ -28,6 +30,16 @@ func main() {
 	// Initialize the database and run migrations
 	db.InitDB(connAddr, migrationsDir)
 
+	duplicateDetectionInterval := 24 * time.Hour
+	if interval := os.Getenv("DUPLICATE_DETECTION_INTERVAL"); interval != "" {
+		var err error
+		duplicateDetectionInterval, err = time.ParseDuration(interval)
+		if err != nil || duplicateDetectionInterval <= 0 {
+			log.Fatalf("Invalid DUPLICATE_DETECTION_INTERVAL %q", interval)
+		}
+	}
+	go utils.RunDuplicateDetection(duplicateDetectionInterval)
+
 	r := router.SetupRouter()
 
 	if err := r.Run(":8080"); err != nil {
@@ -1,21 +1,59 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
 	"github.com/bensiauu/financial-assistance-scheme/models"
 	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errLastSuperAdmin blocks a change that would leave nobody able to manage
+// administrators.
+var errLastSuperAdmin = errors.New("cannot remove the last super_admin")
+
+// keepSuperAdmin returns errLastSuperAdmin if the administrator with the
+// given ID is the only super_admin. It locks every super_admin's row in ID
+// order, so concurrent demotions and deletions cannot both pass or deadlock.
+func keepSuperAdmin(tx *gorm.DB, id uuid.UUID) error {
+	var superAdmins []models.Administrator
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ?", models.RoleSuperAdmin).
+		Order("id").
+		Find(&superAdmins).Error
+	if err != nil {
+		return err
+	}
+	if len(superAdmins) == 1 && superAdmins[0].ID == id {
+		return errLastSuperAdmin
+	}
+	return nil
+}
+
+func newAdministratorResponse(admin models.Administrator) models.AdministratorResponse {
+	return models.AdministratorResponse{
+		ID:        admin.ID,
+		Name:      admin.Name,
+		Email:     admin.Email,
+		Role:      admin.Role,
+		CreatedAt: admin.CreatedAt,
+		UpdatedAt: admin.UpdatedAt,
+	}
+}
+
 func CreateAdministrator(c *gin.Context) {
 	type CreateAdminRequest struct {
 		Name     string `json:"name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required"`
+		Role     string `json:"role" binding:"omitempty,oneof=super_admin scheme_manager caseworker auditor"`
 	}
 
 	var req CreateAdminRequest
@@ -33,6 +71,10 @@ func CreateAdministrator(c *gin.Context) {
 		Name:         req.Name,
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
+		Role:         req.Role,
+	}
+	if admin.Role == "" {
+		admin.Role = models.RoleCaseworker
 	}
 
 	if err := db.DB.Create(&admin).Error; err != nil {
@@ -49,26 +91,33 @@ func CreateAdministrator(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "admin created successfully"})
 }
 
+var administratorListOptions = utils.ListOptions{
+	Filters:     map[string]utils.FilterKind{"email": utils.StringFilter},
+	Sorts:       []string{"name", "email", "created_at"},
+	DefaultSort: "name",
+}
+
 func GetAllAdministrators(c *gin.Context) {
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), administratorListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var admins []models.Administrator
-	if err := db.DB.Find(&admins).Error; err != nil {
+	page, err := query.Find(db.DB, &admins)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	var response []models.AdministratorResponse
+	response := make([]models.AdministratorResponse, 0, len(admins))
 	for _, admin := range admins {
-		response = append(response, models.AdministratorResponse{
-			ID:        admin.ID,
-			Name:      admin.Name,
-			Email:     admin.Email,
-			CreatedAt: admin.CreatedAt,
-			UpdatedAt: admin.UpdatedAt,
-		})
+		response = append(response, newAdministratorResponse(admin))
 	}
 
-	c.JSON(http.StatusOK, response)
-
+	page.Items = response
+	c.JSON(http.StatusOK, page)
 }
 
 func GetAdministratorByID(c *gin.Context) {
@@ -85,15 +134,7 @@ func GetAdministratorByID(c *gin.Context) {
 		return
 	}
 
-	response := models.AdministratorResponse{
-		ID:        admin.ID,
-		Name:      admin.Name,
-		Email:     admin.Email,
-		CreatedAt: admin.CreatedAt,
-		UpdatedAt: admin.UpdatedAt,
-	}
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, newAdministratorResponse(admin))
 }
 
 func UpdateAdministrator(c *gin.Context) {
@@ -101,6 +142,7 @@ func UpdateAdministrator(c *gin.Context) {
 		Name         string `json:"name,omitempty"`
 		Email        string `json:"email,omitempty"`
 		PasswordHash string `json:"password,omitempty"`
+		Role         string `json:"role,omitempty" binding:"omitempty,oneof=super_admin scheme_manager caseworker auditor"`
 	}
 	var prevAdmin models.Administrator
 	var newAdmin Input
@@ -131,8 +173,23 @@ func UpdateAdministrator(c *gin.Context) {
 		}
 		updates["password_hash"] = string(hashedPassword)
 	}
+	if newAdmin.Role != "" {
+		updates["role"] = newAdmin.Role
+	}
 
-	if err := db.DB.Model(&prevAdmin).Updates(updates).Error; err != nil {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if newAdmin.Role != "" && newAdmin.Role != models.RoleSuperAdmin {
+			if err := keepSuperAdmin(tx, prevAdmin.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&prevAdmin).Updates(updates).Error
+	})
+	if errors.Is(err, errLastSuperAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -141,16 +198,33 @@ func UpdateAdministrator(c *gin.Context) {
 }
 
 func DeleteAdministrator(c *gin.Context) {
-	id := c.Param("id")
-	result := db.DB.Delete(&models.Administrator{}, "id = ?", id)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete administrator"})
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "administrator not found"})
 		return
 	}
-
-	if result.RowsAffected == 0 {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// The super_admins are locked before the target so every caller
+		// takes the locks in the same order.
+		if err := keepSuperAdmin(tx, id); err != nil {
+			return err
+		}
+		var admin models.Administrator
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&admin, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&admin).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": "administrator not found"})
 		return
+	case errors.Is(err, errLastSuperAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete administrator"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "administrator deleted successfully"})
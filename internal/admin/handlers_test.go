@@ -90,6 +90,12 @@ func TestCreateAdministrator(t *testing.T) {
 			expectedCode:  http.StatusBadRequest,
 			expectedError: "Field validation for 'Email'",
 		},
+		{
+			name:          "Unknown role",
+			inputJSON:     `{"name": "John Doe", "email": "john@example.com", "password": "password123", "role": "owner"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Field validation for 'Role' failed on the 'oneof' tag",
+		},
 		{
 			name:          "Duplicate email",
 			inputJSON:     `{"name": "John Doe", "email": "john@example.com", "password": "password123"}`,
@@ -162,10 +168,14 @@ func TestGetAllAdministrators(t *testing.T) {
 
 			assert.Equal(t, tt.expectedCode, w.Code)
 
-			var response []models.AdministratorResponse
+			var response struct {
+				Items []models.AdministratorResponse `json:"items"`
+				Total int64                          `json:"total"`
+			}
 			err := json.Unmarshal(w.Body.Bytes(), &response)
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expectedCount, len(response))
+			assert.Equal(t, tt.expectedCount, len(response.Items))
+			assert.Equal(t, int64(tt.expectedCount), response.Total)
 		})
 	}
 }
@@ -282,6 +292,31 @@ func TestUpdateAdministrator(t *testing.T) {
 			expectedCode:  http.StatusBadRequest,
 			expectedError: "json: cannot unmarshal number into Go struct field Input.name of type string",
 		},
+		{
+			name: "Change role",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				db.Create(&models.Administrator{Name: "Root", Email: "root@example.com", PasswordHash: "hashedpassword", Role: models.RoleSuperAdmin})
+				admin := models.Administrator{Name: "John Doe", Email: "john@example.com", PasswordHash: "hashedpassword", Role: models.RoleSuperAdmin}
+				db.Create(&admin)
+				return admin.ID.String()
+			},
+			inputJSON:     `{"role": "auditor"}`,
+			expectedCode:  http.StatusOK,
+			expectedError: "",
+		},
+		{
+			name: "Demote the last super_admin",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				admin := models.Administrator{Name: "John Doe", Email: "john@example.com", PasswordHash: "hashedpassword", Role: models.RoleSuperAdmin}
+				db.Create(&admin)
+				return admin.ID.String()
+			},
+			inputJSON:     `{"role": "caseworker"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: "cannot remove the last super_admin",
+		},
 	}
 
 	for _, tt := range tests {
@@ -334,6 +369,17 @@ func TestDeleteAdministrator(t *testing.T) {
 			expectedCode:  http.StatusNotFound,
 			expectedError: "administrator not found",
 		},
+		{
+			name: "Last super_admin",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				admin := models.Administrator{Name: "John Doe", Email: "john@example.com", PasswordHash: "hashedpassword", Role: models.RoleSuperAdmin}
+				db.Create(&admin)
+				return admin.ID.String()
+			},
+			expectedCode:  http.StatusConflict,
+			expectedError: "cannot remove the last super_admin",
+		},
 	}
 
 	for _, tt := range tests {
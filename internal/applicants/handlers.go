@@ -1,33 +1,38 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/bensiauu/financial-assistance-scheme/internal/middleware"
+	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
 	"github.com/bensiauu/financial-assistance-scheme/models"
 	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateApplicant(c *gin.Context) {
 	var input struct {
-		Name             string `json:"name" binding:"required"`
-		EmploymentStatus string `json:"employment_status,omitempty"`
-		Sex              string `json:"sex,omitempty"`
-		DateOfBirth      string `json:"date_of_birth,omitempty"`
-		LastEmployed     string `json:"last_employed,omitempty"`
-		Income           int    `json:"income,omitempty"`
-		MaritalStatus    string `json:"marital_status,omitempty"`
-		DisabilityStatus string `json:"disability_status,omitempty"`
-		NumberOfChildren int    `json:"number_of_children,omitempty"`
-		Household        []struct {
-			Name             string `json:"name"`
-			Relation         string `json:"relation"`
-			DateOfBirth      string `json:"date_of_birth"`
-			EmploymentStatus string `json:"employment_status"`
-		} `json:"household"`
+		Name             string                 `json:"name" binding:"required"`
+		NationalID       string                 `json:"national_id,omitempty"`
+		EmploymentStatus string                 `json:"employment_status,omitempty"`
+		Sex              string                 `json:"sex,omitempty"`
+		DateOfBirth      string                 `json:"date_of_birth"`
+		LastEmployed     string                 `json:"last_employed,omitempty"`
+		Income           models.Money           `json:"income"`
+		IncomePeriod     string                 `json:"income_period,omitempty"`
+		MaritalStatus    string                 `json:"marital_status,omitempty"`
+		DisabilityStatus string                 `json:"disability_status,omitempty"`
+		NumberOfChildren int                    `json:"number_of_children,omitempty"`
+		Household        []householdMemberInput `json:"household"`
 	}
 
 	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
@@ -35,55 +40,49 @@ func CreateApplicant(c *gin.Context) {
 		return
 	}
 
-	dateOfBirth, err := time.Parse("2006-01-02", input.DateOfBirth)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date of birth"})
+	v := utils.NewValidator()
+	dateOfBirth, _ := v.DateOfBirth("date_of_birth", input.DateOfBirth)
+	lastEmployed := validateLastEmployed(v, input.LastEmployed, dateOfBirth)
+	requiredOneOf(v, "employment_status", input.EmploymentStatus)
+	requiredOneOf(v, "sex", input.Sex)
+	maritalStatus := withDefault(input.MaritalStatus, defaultMaritalStatus)
+	v.OneOf("marital_status", "marital_status", maritalStatus)
+	disabilityStatus := withDefault(input.DisabilityStatus, defaultDisabilityStatus)
+	v.OneOf("disability_status", "disability_status", disabilityStatus)
+	v.Money("income", input.Income)
+	v.OneOf("income_period", "income_period", input.IncomePeriod)
+	v.NonNegative("number_of_children", input.NumberOfChildren)
+	nationalIDs := parseNationalIDs(v, input.NationalID, input.Household)
+	household := validateHousehold(v, input.Household)
+	if !v.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid applicant", "details": v.Errors})
 		return
 	}
 
-	var lastEmployed *time.Time
-	if input.LastEmployed != "" {
-		t, err := time.Parse("2006-01-02", input.LastEmployed)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last employed date"})
-			return
-		}
-		lastEmployed = &t
-	}
-
 	applicant := models.Applicant{
 		Name:             input.Name,
+		NationalID:       nationalIDs.applicant,
 		EmploymentStatus: input.EmploymentStatus,
 		Sex:              input.Sex,
 		DateOfBirth:      dateOfBirth,
 		LastEmployed:     lastEmployed,
 		Income:           input.Income,
-		MaritalStatus:    input.MaritalStatus,
-		DisabilityStatus: input.DisabilityStatus,
+		IncomePeriod:     incomePeriod(input.IncomePeriod),
+		MaritalStatus:    maritalStatus,
+		DisabilityStatus: disabilityStatus,
 		NumberOfChildren: input.NumberOfChildren,
 	}
 
-	err = db.DB.Transaction(func(tx *gorm.DB) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&applicant).Error; err != nil {
 			return err
 		}
 
 		// Create Household members
-		for _, h := range input.Household {
-			hDOB, err := time.Parse("2006-01-02", h.DateOfBirth)
-			if err != nil {
-				return fmt.Errorf("invalid date of birth for household member: %s", h.Name)
-			}
-
-			householdMember := models.HouseholdMember{
-				ApplicantID:      applicant.ID,
-				Name:             h.Name,
-				Relation:         h.Relation,
-				DateOfBirth:      hDOB,
-				EmploymentStatus: h.EmploymentStatus,
-			}
-
-			if err := tx.Create(&householdMember).Error; err != nil {
+		for i, member := range household {
+			member.ApplicantID = applicant.ID
+			member.NationalID = nationalIDs.household[i]
+			if err := tx.Create(&member).Error; err != nil {
 				return err
 			}
 		}
@@ -92,6 +91,10 @@ func CreateApplicant(c *gin.Context) {
 	})
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "national ID is already registered to another applicant"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create applicant and household members"})
 		return
 	}
@@ -99,38 +102,39 @@ func CreateApplicant(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "applicant created successfully"})
 }
 
+var applicantListOptions = utils.ListOptions{
+	Filters: map[string]utils.FilterKind{
+		"employment_status": utils.StringFilter,
+		"sex":               utils.StringFilter,
+		"marital_status":    utils.StringFilter,
+		"disability_status": utils.StringFilter,
+	},
+	Sorts:       []string{"name", "date_of_birth", "income", "created_at"},
+	DefaultSort: "name",
+}
+
 func GetAllApplicants(c *gin.Context) {
-	var applicants []models.Applicant
-	// Use Preload to load Household members
-	if err := db.DB.Preload("Household").Find(&applicants).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), applicantListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if no applicants were found
-	if len(applicants) == 0 {
-		c.JSON(http.StatusOK, []models.ApplicantResponse{})
+	var applicants []models.Applicant
+	// Use Preload to load Household members
+	page, err := query.Find(db.DB, &applicants, "Household")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	response := make([]models.ApplicantResponse, 0)
 	for _, applicant := range applicants {
-		response = append(response, models.ApplicantResponse{
-			ID:               applicant.ID,
-			Name:             applicant.Name,
-			EmploymentStatus: applicant.EmploymentStatus,
-			Sex:              applicant.Sex,
-			DateOfBirth:      applicant.DateOfBirth,
-			LastEmployed:     applicant.LastEmployed,
-			Income:           applicant.Income,
-			MaritalStatus:    applicant.MaritalStatus,
-			DisabilityStatus: applicant.DisabilityStatus,
-			NumberOfChildren: applicant.NumberOfChildren,
-			Household:        applicant.Household,
-		})
+		response = append(response, newApplicantResponse(applicant, middleware.CanViewNationalIDs(c)))
 	}
 
-	c.JSON(http.StatusOK, response)
+	page.Items = response
+	c.JSON(http.StatusOK, page)
 }
 func GetApplicantByID(c *gin.Context) {
 	var applicant models.Applicant
@@ -147,156 +151,787 @@ func GetApplicantByID(c *gin.Context) {
 		return
 	}
 
-	response := models.ApplicantResponse{
+	c.Header("ETag", versionETag(applicant.Version))
+	c.JSON(http.StatusOK, newApplicantResponse(applicant, middleware.CanViewNationalIDs(c)))
+}
+
+// newApplicantResponse builds the response for an applicant whose household
+// has been loaded. National IDs are masked unless revealNationalIDs is set.
+func newApplicantResponse(applicant models.Applicant, revealNationalIDs bool) models.ApplicantResponse {
+	household := make([]models.HouseholdMember, 0, len(applicant.Household))
+	for _, member := range applicant.Household {
+		household = append(household, newHouseholdMemberResponse(member, revealNationalIDs))
+	}
+
+	nationalID := ""
+	if applicant.NationalID != nil {
+		nationalID = *applicant.NationalID
+		if !revealNationalIDs {
+			nationalID = utils.MaskNationalID(nationalID)
+		}
+	}
+
+	return models.ApplicantResponse{
 		ID:               applicant.ID,
 		Name:             applicant.Name,
+		NationalID:       nationalID,
 		EmploymentStatus: applicant.EmploymentStatus,
 		Sex:              applicant.Sex,
 		DateOfBirth:      applicant.DateOfBirth,
 		LastEmployed:     applicant.LastEmployed,
+		Income:           applicant.Income,
+		IncomePeriod:     incomePeriod(applicant.IncomePeriod),
+		HouseholdIncome:  applicant.HouseholdIncome(),
+		PerCapitaIncome:  applicant.PerCapitaIncome(),
 		MaritalStatus:    applicant.MaritalStatus,
 		DisabilityStatus: applicant.DisabilityStatus,
 		NumberOfChildren: applicant.NumberOfChildren,
-		Household:        applicant.Household,
+		Household:        household,
+		Version:          applicant.Version,
 	}
+}
+
+// applicantSearchOptions only sorts search results; the search terms are not
+// exact-match filters.
+var applicantSearchOptions = utils.ListOptions{
+	Sorts:       []string{"score", "name", "date_of_birth"},
+	DefaultSort: "-score",
+}
+
+// likeEscaper escapes the LIKE wildcards, with backslash as the escape
+// character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// substringPattern returns an ILIKE pattern matching text anywhere in a
+// value, treating any wildcards in text literally.
+func substringPattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
 
-	c.JSON(http.StatusOK, response)
+// applicantSearchResult is an applicant matching a search with how closely
+// their name and household members' names match, from 0 to 1 per term.
+type applicantSearchResult struct {
+	models.ApplicantResponse
+	Score float64 `json:"score"`
+}
+
+// SearchApplicants finds applicants by fuzzy name (name), exact date of birth
+// (date_of_birth) and fuzzy household member name (member). Names match on
+// trigram similarity or as a case-insensitive substring.
+func SearchApplicants(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	member := strings.TrimSpace(c.Query("member"))
+	dateOfBirth := c.Query("date_of_birth")
+	if name == "" && member == "" && dateOfBirth == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one of name, date_of_birth or member is required"})
+		return
+	}
+
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), applicantSearchOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	search := db.DB.Model(&models.Applicant{})
+	scores := []string{}
+	var scoreArgs []interface{}
+	if name != "" {
+		search = search.Where(`(applicants.name % ? OR applicants.name ILIKE ? ESCAPE '\')`, name, substringPattern(name))
+		scores = append(scores, "similarity(applicants.name, ?)")
+		scoreArgs = append(scoreArgs, name)
+	}
+	if member != "" {
+		search = search.Where(`EXISTS (SELECT 1 FROM household_members WHERE household_members.applicant_id = applicants.id AND (household_members.name % ? OR household_members.name ILIKE ? ESCAPE '\'))`, member, substringPattern(member))
+		scores = append(scores, "COALESCE((SELECT MAX(similarity(household_members.name, ?)) FROM household_members WHERE household_members.applicant_id = applicants.id), 0)")
+		scoreArgs = append(scoreArgs, member)
+	}
+	if dateOfBirth != "" {
+		dob, err := time.Parse("2006-01-02", dateOfBirth)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date_of_birth, expected YYYY-MM-DD"})
+			return
+		}
+		search = search.Where("applicants.date_of_birth >= ? AND applicants.date_of_birth < ?", dob, dob.AddDate(0, 0, 1))
+	}
+	if len(scores) == 0 {
+		scores = append(scores, "0")
+	}
+	search = search.Session(&gorm.Session{})
+
+	var total int64
+	if err := search.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var matches []struct {
+		ID    uuid.UUID
+		Score float64
+	}
+	err = search.
+		Select("applicants.id, ("+strings.Join(scores, " + ")+") AS score", scoreArgs...).
+		Order(query.Order).
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Scan(&matches).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	var applicants []models.Applicant
+	if len(ids) > 0 {
+		if err := db.DB.Preload("Household").Where("id IN ?", ids).Find(&applicants).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Return the applicants in the order they matched.
+	results := make([]applicantSearchResult, 0, len(matches))
+	for _, match := range matches {
+		for _, applicant := range applicants {
+			if applicant.ID == match.ID {
+				results = append(results, applicantSearchResult{
+					ApplicantResponse: newApplicantResponse(applicant, middleware.CanViewNationalIDs(c)),
+					Score:             match.Score,
+				})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, utils.Page{Items: results, Page: query.Page, PageSize: query.PageSize, Total: total})
 }
 
 type updateApplicantInput struct {
-	Name             *string `json:"name,omitempty"`
-	DateOfBirth      *string `json:"date_of_birth,omitempty"`
-	EmploymentStatus *string `json:"employment_status,omitempty"`
-	Sex              *string `json:"sex,omitempty"`
-	LastEmployed     *string `json:"last_employed,omitempty"`
-	Income           *int    `json:"income,omitempty"`
-	MaritalStatus    *string `json:"marital_status,omitempty"`
-	DisabilityStatus *string `json:"disability_status,omitempty"`
-	NumberOfChildren *int    `json:"number_of_children,omitempty"`
-	Household        *[]struct {
-		Name             string `json:"name"`
-		Relation         string `json:"relation"`
-		DateOfBirth      string `json:"date_of_birth"`
-		EmploymentStatus string `json:"employment_status"`
-	} `json:"household,omitempty"`
-}
-
-func checkForApplicantUpdates(newApplicant updateApplicantInput) (map[string]interface{}, error) {
+	Name             *string                 `json:"name,omitempty"`
+	NationalID       *string                 `json:"national_id,omitempty"`
+	DateOfBirth      *string                 `json:"date_of_birth,omitempty"`
+	EmploymentStatus *string                 `json:"employment_status,omitempty"`
+	Sex              *string                 `json:"sex,omitempty"`
+	LastEmployed     *string                 `json:"last_employed,omitempty"`
+	Income           *models.Money           `json:"income,omitempty"`
+	IncomePeriod     *string                 `json:"income_period,omitempty"`
+	MaritalStatus    *string                 `json:"marital_status,omitempty"`
+	DisabilityStatus *string                 `json:"disability_status,omitempty"`
+	NumberOfChildren *int                    `json:"number_of_children,omitempty"`
+	Household        *[]householdMemberInput `json:"household,omitempty"`
+	Version          *int                    `json:"version,omitempty"` // The version being changed; also accepted as If-Match
+}
+
+type householdMemberInput struct {
+	Name             string       `json:"name"`
+	NationalID       string       `json:"national_id,omitempty"`
+	Relation         string       `json:"relation"`
+	DateOfBirth      string       `json:"date_of_birth"`
+	EmploymentStatus string       `json:"employment_status"`
+	Income           models.Money `json:"income"`
+	IncomePeriod     string       `json:"income_period,omitempty"`
+}
+
+// incomePeriod defaults an income period to monthly.
+func incomePeriod(period string) string {
+	return withDefault(period, models.IncomeMonthly)
+}
+
+// Defaults for applicant fields that may be left out, matching the column
+// defaults.
+const (
+	defaultMaritalStatus    = "single"
+	defaultDisabilityStatus = "none"
+)
+
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// requiredOneOf checks that a field is given and holds a value from its
+// vocabulary.
+func requiredOneOf(v *utils.Validator, path, value string) {
+	field := path[strings.LastIndex(path, ".")+1:]
+	if v.Required(path, value) {
+		v.OneOf(path, field, value)
+	}
+}
+
+// nationalIDs are the validated national IDs of an applicant and each of
+// their household members, nil where none was given.
+type nationalIDs struct {
+	applicant *string
+	household []*string
+}
+
+// parseNationalIDs normalizes and validates the national IDs of an applicant
+// and their household, which must all be different.
+func parseNationalIDs(v *utils.Validator, applicantID string, household []householdMemberInput) nationalIDs {
+	var ids nationalIDs
+	seen := map[string]bool{}
+
+	parse := func(raw, path string) *string {
+		id := utils.NormalizeNationalID(raw)
+		if id == "" {
+			return nil
+		}
+		if err := utils.ValidateNationalID(id); err != nil {
+			v.Fail(path, err.Error())
+			return nil
+		}
+		if seen[id] {
+			v.Fail(path, "is used more than once in the household")
+			return nil
+		}
+		seen[id] = true
+		return &id
+	}
+
+	ids.applicant = parse(applicantID, "national_id")
+	for i, member := range household {
+		ids.household = append(ids.household, parse(member.NationalID, fmt.Sprintf("household[%d].national_id", i)))
+	}
+	return ids
+}
+
+// validateLastEmployed parses an optional last employed date, which cannot be
+// before the date of birth.
+func validateLastEmployed(v *utils.Validator, value string, dateOfBirth time.Time) *time.Time {
+	if value == "" {
+		return nil
+	}
+	lastEmployed, ok := v.Date("last_employed", value)
+	if !ok {
+		return nil
+	}
+	if !dateOfBirth.IsZero() && lastEmployed.Before(dateOfBirth) {
+		v.Fail("last_employed", "must not be before date_of_birth")
+		return nil
+	}
+	return &lastEmployed
+}
+
+// validateHousehold checks each household member and the household's
+// relations, and returns the members as models without an applicant or
+// national ID.
+func validateHousehold(v *utils.Validator, household []householdMemberInput) []models.HouseholdMember {
+	members := make([]models.HouseholdMember, 0, len(household))
+	for i, input := range household {
+		members = append(members, validateHouseholdMember(v, fmt.Sprintf("household[%d].", i), input))
+	}
+	checkRelationLimits(v, members, func(i int) string { return fmt.Sprintf("household[%d].relation", i) })
+	return members
+}
+
+// validateHouseholdMember checks a household member whose fields are
+// addressed by prefix and returns it as a model without an applicant or
+// national ID.
+func validateHouseholdMember(v *utils.Validator, prefix string, input householdMemberInput) models.HouseholdMember {
+	v.Required(prefix+"name", input.Name)
+	requiredOneOf(v, prefix+"relation", input.Relation)
+	requiredOneOf(v, prefix+"employment_status", input.EmploymentStatus)
+	dateOfBirth, _ := v.DateOfBirth(prefix+"date_of_birth", input.DateOfBirth)
+	v.Money(prefix+"income", input.Income)
+	v.OneOf(prefix+"income_period", "income_period", input.IncomePeriod)
+
+	return models.HouseholdMember{
+		Name:             input.Name,
+		Relation:         input.Relation,
+		DateOfBirth:      dateOfBirth,
+		EmploymentStatus: input.EmploymentStatus,
+		Income:           input.Income,
+		IncomePeriod:     incomePeriod(input.IncomePeriod),
+	}
+}
+
+// relationLimits caps how many members of a household may have each relation
+// to the applicant.
+var relationLimits = map[string]int{"spouse": 1, "father": 1, "mother": 1}
+
+// checkRelationLimits reports every member beyond the limit for their
+// relation, addressed by path.
+func checkRelationLimits(v *utils.Validator, members []models.HouseholdMember, path func(i int) string) {
+	counts := map[string]int{}
+	for i, member := range members {
+		limit, ok := relationLimits[member.Relation]
+		if !ok {
+			continue
+		}
+		counts[member.Relation]++
+		if counts[member.Relation] > limit {
+			v.Fail(path(i), fmt.Sprintf("a household may have at most %d %s", limit, member.Relation))
+		}
+	}
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// checkForApplicantUpdates validates the fields being updated against the
+// applicant they will be applied to and returns the column updates.
+func checkForApplicantUpdates(v *utils.Validator, newApplicant updateApplicantInput, original models.Applicant) map[string]interface{} {
 	updates := make(map[string]interface{})
 
-	if newApplicant.Name != nil {
+	if newApplicant.Name != nil && v.Required("name", *newApplicant.Name) {
 		updates["name"] = *newApplicant.Name
 	}
 	if newApplicant.EmploymentStatus != nil {
+		requiredOneOf(v, "employment_status", *newApplicant.EmploymentStatus)
 		updates["employment_status"] = *newApplicant.EmploymentStatus
 	}
 	if newApplicant.Sex != nil {
+		requiredOneOf(v, "sex", *newApplicant.Sex)
 		updates["sex"] = *newApplicant.Sex
 	}
+	dateOfBirth := original.DateOfBirth
 	if newApplicant.DateOfBirth != nil {
-		parsedDate, err := time.Parse("2006-01-02", *newApplicant.DateOfBirth)
-		if err != nil {
-			return nil, fmt.Errorf("invalid date format for DateOfBirth")
-		}
-		updates["date_of_birth"] = parsedDate
+		dateOfBirth, _ = v.DateOfBirth("date_of_birth", *newApplicant.DateOfBirth)
+		updates["date_of_birth"] = dateOfBirth
 	}
 	if newApplicant.LastEmployed != nil {
-		parsedDate, err := time.Parse("2006-01-02", *newApplicant.LastEmployed)
-		if err != nil {
-			return nil, fmt.Errorf("invalid date format for LastEmployed")
-		}
-		updates["last_employed"] = parsedDate
+		updates["last_employed"] = validateLastEmployed(v, *newApplicant.LastEmployed, dateOfBirth)
+	} else if newApplicant.DateOfBirth != nil && original.LastEmployed != nil && !dateOfBirth.IsZero() && original.LastEmployed.Before(dateOfBirth) {
+		v.Fail("date_of_birth", "must not be after last_employed")
 	}
 	if newApplicant.MaritalStatus != nil {
+		requiredOneOf(v, "marital_status", *newApplicant.MaritalStatus)
 		updates["marital_status"] = *newApplicant.MaritalStatus
 	}
 	if newApplicant.DisabilityStatus != nil {
+		requiredOneOf(v, "disability_status", *newApplicant.DisabilityStatus)
 		updates["disability_status"] = *newApplicant.DisabilityStatus
 	}
 	if newApplicant.Income != nil {
-		updates["income"] = newApplicant.Income
+		v.Money("income", *newApplicant.Income)
+		updates["income"] = *newApplicant.Income
+	}
+	if newApplicant.IncomePeriod != nil {
+		v.OneOf("income_period", "income_period", *newApplicant.IncomePeriod)
+		updates["income_period"] = incomePeriod(*newApplicant.IncomePeriod)
 	}
 	if newApplicant.NumberOfChildren != nil {
-		updates["number_of_children"] = newApplicant.NumberOfChildren
+		v.NonNegative("number_of_children", *newApplicant.NumberOfChildren)
+		updates["number_of_children"] = *newApplicant.NumberOfChildren
 	}
 
-	return updates, nil
+	return updates
 }
 
+// UpdateApplicant validates the whole change against the stored applicant
+// before writing anything, then updates the applicant and replaces their
+// household in one transaction. The client must send the version it read, in
+// If-Match or the version field, and gets 412 instead of overwriting a newer
+// change; without either it gets 428.
 func UpdateApplicant(c *gin.Context) {
+	applicantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	}
 
-	var originalApplicant models.Applicant
 	var newApplicant updateApplicantInput
-	id := c.Param("id")
-
 	if err := c.ShouldBindBodyWithJSON(&newApplicant); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := db.DB.Preload("Household").First(&originalApplicant, "id = ?", id).Error; err != nil {
-		if gorm.ErrRecordNotFound == err {
-			c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
-			return
+	expected, err := expectedVersion(c, newApplicant.Version)
+	if err != nil {
+		respondVersionError(c, err)
+		return
+	}
+
+	var applicant models.Applicant
+	var flagged []uuid.UUID
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var household []models.HouseholdMember
+		applicant, household, err = lockHousehold(tx, applicantID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(applicant, expected); err != nil {
+			return err
 		}
+
+		v := utils.NewValidator()
+		updates := checkForApplicantUpdates(v, newApplicant, applicant)
+
+		// Check the national IDs the applicant and household will have after the update.
+		applicantNationalID := ""
+		if applicant.NationalID != nil {
+			applicantNationalID = *applicant.NationalID
+		}
+		if newApplicant.NationalID != nil {
+			applicantNationalID = *newApplicant.NationalID
+		}
+		var inputs []householdMemberInput
+		var members []models.HouseholdMember
+		if newApplicant.Household != nil {
+			inputs = *newApplicant.Household
+			members = validateHousehold(v, inputs)
+		} else {
+			for _, member := range household {
+				input := householdMemberInput{Name: member.Name}
+				if member.NationalID != nil {
+					input.NationalID = *member.NationalID
+				}
+				inputs = append(inputs, input)
+			}
+		}
+		nationalIDs := parseNationalIDs(v, applicantNationalID, inputs)
+		if !v.Valid() {
+			return invalidApplicantError(v.Errors)
+		}
+		if newApplicant.NationalID != nil {
+			updates["national_id"] = nationalIDs.applicant
+		}
+
+		updates["version"] = gorm.Expr("version + 1")
+		if err := tx.Model(&applicant).Updates(updates).Error; err != nil {
+			return err
+		}
+		applicant.Version++
+
+		if newApplicant.Household != nil {
+			if err := tx.Where("applicant_id = ?", applicant.ID).Delete(&models.HouseholdMember{}).Error; err != nil {
+				return err
+			}
+			for i, member := range members {
+				member.ApplicantID = applicant.ID
+				member.NationalID = nationalIDs.household[i]
+				if err := tx.Create(&member).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		flagged, err = utils.FlagIneligibleApplications(tx, applicant.ID, utils.Clock())
+		return err
+	})
+	if err != nil {
+		respondApplicantError(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(applicant.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "applicant updated successfully",
+		"version":                 applicant.Version,
+		"ineligible_applications": flagged,
+	})
+}
+
+func DeleteApplicant(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the applicant and their applications so that no application
+		// or status change can be added between the check and the delete.
+		var applicant models.Applicant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&applicant, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var applications []models.Application
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("applicant_id = ?", id).Find(&applications).Error; err != nil {
+			return err
+		}
+
+		// Deleting the applicant would delete their applications and so the
+		// applications' status history.
+		var changeCount int64
+		err := tx.Model(&models.ApplicationStatusChange{}).
+			Joins("JOIN applications ON applications.id = application_status_changes.application_id").
+			Where("applications.applicant_id = ?", id).
+			Count(&changeCount).Error
+		if err != nil {
+			return err
+		}
+		if changeCount > 0 {
+			return errApplicantHasHistory
+		}
+
+		return tx.Delete(&applicant).Error
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	case errors.Is(err, errApplicantHasHistory) || utils.IsStatusHistoryViolation(err):
+		c.JSON(http.StatusConflict, gin.H{"error": errApplicantHasHistory.Error()})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	updates, err := checkForApplicantUpdates(newApplicant)
+	c.JSON(http.StatusOK, gin.H{"message": "applicant deleted successfully"})
+}
+
+// errApplicantHasHistory blocks deleting an applicant whose applications
+// have an audit trail.
+var errApplicantHasHistory = errors.New("applicant has applications with status history and cannot be deleted")
+
+var duplicateListOptions = utils.ListOptions{
+	Filters:     map[string]utils.FilterKind{"applicant_id": utils.UUIDFilter},
+	Sorts:       []string{"score", "detected_at"},
+	DefaultSort: "-score",
+}
+
+// duplicateResponse is a suspected duplicate pair with both applicants.
+type duplicateResponse struct {
+	models.DuplicateCandidate
+	Applicant models.ApplicantResponse `json:"applicant"`
+	Duplicate models.ApplicantResponse `json:"duplicate"`
+}
+
+func GetDuplicateApplicants(c *gin.Context) {
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), duplicateListOptions)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := db.DB.Model(&originalApplicant).Updates(updates).Error; err != nil {
+	var candidates []models.DuplicateCandidate
+	page, err := query.Find(db.DB, &candidates)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if newApplicant.Household != nil {
-		// Clear existing household members
-		db.DB.Where("applicant_id = ?", originalApplicant.ID).Delete(&models.HouseholdMember{})
+	ids := make([]uuid.UUID, 0, 2*len(candidates))
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.ApplicantID, candidate.DuplicateID)
+	}
+	var applicants []models.Applicant
+	if len(ids) > 0 {
+		if err := db.DB.Preload("Household").Where("id IN ?", ids).Find(&applicants).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	reveal := middleware.CanViewNationalIDs(c)
+	responses := make(map[uuid.UUID]models.ApplicantResponse, len(applicants))
+	for _, applicant := range applicants {
+		responses[applicant.ID] = newApplicantResponse(applicant, reveal)
+	}
 
-		// Add new household members
-		for _, member := range *newApplicant.Household {
-			hDOB, err := time.Parse("2006-01-02", member.DateOfBirth)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date of birth"})
-				return
-			}
+	items := make([]duplicateResponse, 0, len(candidates))
+	for _, candidate := range candidates {
+		items = append(items, duplicateResponse{
+			DuplicateCandidate: candidate,
+			Applicant:          responses[candidate.ApplicantID],
+			Duplicate:          responses[candidate.DuplicateID],
+		})
+	}
 
-			householdMember := models.HouseholdMember{
-				ApplicantID:      originalApplicant.ID,
-				Name:             member.Name,
-				Relation:         member.Relation,
-				DateOfBirth:      hDOB,
-				EmploymentStatus: member.EmploymentStatus,
-			}
-			if err := db.DB.Create(&householdMember).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-		}
+	page.Items = items
+	c.JSON(http.StatusOK, page)
+}
+
+// DetectDuplicateApplicants runs the duplicate detection job immediately.
+func DetectDuplicateApplicants(c *gin.Context) {
+	count, err := utils.DetectDuplicateApplicants(db.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "applicant updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "duplicate detection completed", "suspected_duplicates": count})
+}
+
+// mergeConflictError blocks a merge that would leave the surviving applicant
+// with two active applications for the same scheme.
+type mergeConflictError struct {
+	SchemeIDs []uuid.UUID
+}
 
+func (e *mergeConflictError) Error() string {
+	return "both applicants have active applications for the same schemes"
 }
-func DeleteApplicant(c *gin.Context) {
-	id := c.Param("id")
 
-	result := db.DB.Delete(&models.Applicant{}, "id = ?", id)
+// MergeApplicants merges the applicant given as duplicate_id into the
+// applicant in the path. The duplicate's applications and household members
+// move to the surviving applicant, household members they already share are
+// dropped, and the duplicate is deleted. The merge is recorded with a copy of
+// the duplicate as it was.
+func MergeApplicants(c *gin.Context) {
+	survivorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	var input struct {
+		DuplicateID uuid.UUID `json:"duplicate_id" binding:"required"`
+	}
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.DuplicateID == survivorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "an applicant cannot be merged into itself"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+	var merge models.ApplicantMerge
+	var flagged []uuid.UUID
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock both applicants in a fixed order so concurrent merges cannot deadlock.
+		var applicants []models.Applicant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uuid.UUID{survivorID, input.DuplicateID}).
+			Order("id").
+			Find(&applicants).Error
+		if err != nil {
+			return err
+		}
+		if len(applicants) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var survivor, duplicate models.Applicant
+		if err := tx.Preload("Household").First(&survivor, "id = ?", survivorID).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Household").First(&duplicate, "id = ?", input.DuplicateID).Error; err != nil {
+			return err
+		}
+
+		var conflicts []uuid.UUID
+		err = tx.Model(&models.Application{}).
+			Where("applicant_id = ? AND status IN ?", duplicate.ID, models.ActiveApplicationStatuses).
+			Where("scheme_id IN (?)", tx.Model(&models.Application{}).Select("scheme_id").
+				Where("applicant_id = ? AND status IN ?", survivor.ID, models.ActiveApplicationStatuses)).
+			Pluck("scheme_id", &conflicts).Error
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return &mergeConflictError{SchemeIDs: conflicts}
+		}
+
+		// The merged household must keep within the relation limits.
+		household := append([]models.HouseholdMember(nil), survivor.Household...)
+		for _, member := range duplicate.Household {
+			if !isSameMember(member, survivor.Household) {
+				household = append(household, member)
+			}
+		}
+		v := utils.NewValidator()
+		checkRelationLimits(v, household, func(i int) string { return fmt.Sprintf("household[%d].relation", i) })
+		if !v.Valid() {
+			return invalidApplicantError(v.Errors)
+		}
+
+		snapshot, err := json.Marshal(duplicate)
+		if err != nil {
+			return err
+		}
+
+		moved := tx.Model(&models.Application{}).Where("applicant_id = ?", duplicate.ID).Update("applicant_id", survivor.ID)
+		if moved.Error != nil {
+			return moved.Error
+		}
+
+		householdMoved := 0
+		for _, member := range duplicate.Household {
+			if isSameMember(member, survivor.Household) {
+				if err := tx.Delete(&member).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Model(&member).Update("applicant_id", survivor.ID).Error; err != nil {
+				return err
+			}
+			householdMoved++
+		}
+
+		if err := tx.Delete(&duplicate).Error; err != nil {
+			return err
+		}
+		// Keep the duplicate's national ID if the survivor has none. The
+		// duplicate is deleted first to free up the unique index.
+		if survivor.NationalID == nil && duplicate.NationalID != nil {
+			if err := tx.Model(&survivor).Update("national_id", duplicate.NationalID).Error; err != nil {
+				return err
+			}
+		}
+		err = tx.Where("applicant_id = ? OR duplicate_id = ?", duplicate.ID, duplicate.ID).Delete(&models.DuplicateCandidate{}).Error
+		if err != nil {
+			return err
+		}
+
+		merge = models.ApplicantMerge{
+			SurvivorID:        survivor.ID,
+			MergedID:          duplicate.ID,
+			MergedApplicant:   snapshot,
+			MergedBy:          middleware.Subject(c),
+			ApplicationsMoved: int(moved.RowsAffected),
+			HouseholdMoved:    householdMoved,
+		}
+		if err := tx.Create(&merge).Error; err != nil {
+			return err
+		}
+
+		if err := bumpVersion(tx, &survivor); err != nil {
+			return err
+		}
+
+		// The survivor's household may have grown.
+		flagged, err = utils.FlagIneligibleApplications(tx, survivor.ID, utils.Clock())
+		return err
+	})
+	if err != nil {
+		var conflictErr *mergeConflictError
+		var invalidErr invalidApplicantError
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		case errors.As(err, &invalidErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid applicant", "details": invalidErr})
+		case errors.As(err, &conflictErr):
+			c.JSON(http.StatusConflict, gin.H{"error": conflictErr.Error(), "scheme_ids": conflictErr.SchemeIDs})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "applicant deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "applicants merged successfully",
+		"merge_id":                merge.ID,
+		"applications_moved":      merge.ApplicationsMoved,
+		"household_moved":         merge.HouseholdMoved,
+		"ineligible_applications": flagged,
+	})
+}
+
+// isSameMember reports whether a household member is already in a household,
+// by national ID or by name and date of birth.
+func isSameMember(member models.HouseholdMember, household []models.HouseholdMember) bool {
+	for _, other := range household {
+		if member.NationalID != nil && other.NationalID != nil {
+			if *member.NationalID == *other.NationalID {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(member.Name, other.Name) && member.DateOfBirth.Equal(other.DateOfBirth) {
+			return true
+		}
+	}
+	return false
 }
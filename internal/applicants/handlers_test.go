@@ -28,7 +28,8 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Migrate the schema
-	testDB.AutoMigrate(&models.Applicant{}, &models.HouseholdMember{})
+	testDB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm")
+	testDB.AutoMigrate(&models.Applicant{}, &models.HouseholdMember{}, &models.Scheme{}, &models.SchemeVersion{}, &models.Application{}, &models.DuplicateCandidate{}, &models.ApplicantMerge{}, &models.ApplicationStatusChange{})
 
 	db.DB = testDB
 
@@ -40,6 +41,12 @@ func setupTestDB(t *testing.T) *gorm.DB {
 
 		sqlDB.Exec("DROP TABLE IF EXISTS applicants CASCADE")
 		sqlDB.Exec("DROP TABLE IF EXISTS household_members CASCADE")
+		sqlDB.Exec("DROP TABLE IF EXISTS schemes CASCADE")
+		sqlDB.Exec("DROP TABLE IF EXISTS scheme_versions CASCADE")
+		sqlDB.Exec("DROP TABLE IF EXISTS applications CASCADE")
+		sqlDB.Exec("DROP TABLE IF EXISTS duplicate_candidates CASCADE")
+		sqlDB.Exec("DROP TABLE IF EXISTS applicant_merges CASCADE")
+		sqlDB.Exec("DROP TABLE IF EXISTS application_status_changes CASCADE")
 		sqlDB.Close()
 	})
 
@@ -50,9 +57,17 @@ func setupRouter() *gin.Engine {
 	router := gin.Default()
 	router.POST("/api/applicants", handlers.CreateApplicant)
 	router.GET("/api/applicants", handlers.GetAllApplicants)
+	router.GET("/api/applicants/search", handlers.SearchApplicants)
 	router.GET("/api/applicants/:id", handlers.GetApplicantByID)
 	router.PUT("/api/applicants/:id", handlers.UpdateApplicant)
 	router.DELETE("/api/applicants/:id", handlers.DeleteApplicant)
+	router.GET("/api/applicants/duplicates", handlers.GetDuplicateApplicants)
+	router.POST("/api/applicants/duplicates/detect", handlers.DetectDuplicateApplicants)
+	router.POST("/api/applicants/:id/merge", handlers.MergeApplicants)
+	router.GET("/api/applicants/:id/household", handlers.GetHousehold)
+	router.POST("/api/applicants/:id/household", handlers.AddHouseholdMember)
+	router.PUT("/api/applicants/:id/household/:memberId", handlers.UpdateHouseholdMember)
+	router.DELETE("/api/applicants/:id/household/:memberId", handlers.RemoveHouseholdMember)
 	return router
 }
 
@@ -87,10 +102,117 @@ func TestCreateApplicant(t *testing.T) {
 			name: "Invalid date format",
 			inputJSON: `{
 		        "name": "John Doe",
+		        "employment_status": "employed",
+		        "sex": "male",
 		        "date_of_birth": "01-01-1990"
 		    }`,
 			expectedCode:  http.StatusBadRequest,
-			expectedError: "Invalid date of birth",
+			expectedError: `{"path":"date_of_birth","message":"must be a date in YYYY-MM-DD format"}`,
+		},
+		{
+			name: "Invalid national ID",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "employment_status": "employed",
+		        "sex": "male",
+		        "national_id": "S1234567A",
+		        "date_of_birth": "1990-01-01"
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"path":"national_id","message":"has an invalid check letter"}`,
+		},
+		{
+			name: "National ID repeated in household",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "employment_status": "employed",
+		        "sex": "male",
+		        "national_id": "S1234567D",
+		        "date_of_birth": "1990-01-01",
+		        "household": [
+		            {"name": "Jane Doe", "national_id": "s1234567d", "relation": "spouse", "date_of_birth": "1992-01-01", "employment_status": "employed"}
+		        ]
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"path":"household[0].national_id","message":"is used more than once in the household"}`,
+		},
+		{
+			name: "Missing date of birth",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "employment_status": "employed"
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"path":"date_of_birth","message":"is required"}`,
+		},
+		{
+			name: "Values outside the controlled vocabularies",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "employment_status": "unemploy",
+		        "sex": "M",
+		        "date_of_birth": "1990-01-01",
+		        "household": [
+		            {"name": "Jane Doe", "relation": "cousin", "date_of_birth": "1992-01-01", "employment_status": "employed"}
+		        ]
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `"details":[{"path":"employment_status","message":"unknown employment_status \"unemploy\", expected one of employed, self_employed, unemployed, retired, homemaker, preschool, primary_school, secondary_school, tertiary"},{"path":"sex","message":"unknown sex \"M\", expected one of male, female"},{"path":"household[0].relation","message":"unknown relation \"cousin\", expected one of spouse, son, daughter, child, father, mother, sibling, grandparent, grandchild, other"}]`,
+		},
+		{
+			name: "Missing controlled values",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "sex": "",
+		        "date_of_birth": "1990-01-01",
+		        "household": [
+		            {"name": "Jane Doe", "relation": "spouse", "date_of_birth": "1992-01-01"}
+		        ]
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `"details":[{"path":"employment_status","message":"is required"},{"path":"sex","message":"is required"},{"path":"household[0].employment_status","message":"is required"}]`,
+		},
+		{
+			name: "Impossible dates",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "employment_status": "employed",
+		        "sex": "male",
+		        "date_of_birth": "1880-01-01",
+		        "last_employed": "2999-01-01",
+		        "household": [
+		            {"name": "Baby Doe", "relation": "son", "date_of_birth": "2999-01-01", "employment_status": "preschool"}
+		        ]
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `"details":[{"path":"date_of_birth","message":"implies an age over 130"},{"path":"last_employed","message":"must not be in the future"},{"path":"household[0].date_of_birth","message":"must not be in the future"}]`,
+		},
+		{
+			name: "Last employed before date of birth",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "employment_status": "employed",
+		        "sex": "male",
+		        "date_of_birth": "1990-01-01",
+		        "last_employed": "1980-01-01"
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"path":"last_employed","message":"must not be before date_of_birth"}`,
+		},
+		{
+			name: "Two spouses",
+			inputJSON: `{
+		        "name": "John Doe",
+		        "employment_status": "employed",
+		        "sex": "male",
+		        "date_of_birth": "1990-01-01",
+		        "household": [
+		            {"name": "Jane Doe", "relation": "spouse", "date_of_birth": "1992-01-01", "employment_status": "employed"},
+		            {"name": "Joan Doe", "relation": "spouse", "date_of_birth": "1993-01-01", "employment_status": "employed"}
+		        ]
+		    }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"path":"household[1].relation","message":"a household may have at most 1 spouse"}`,
 		},
 		{
 			name: "Missing name",
@@ -105,6 +227,8 @@ func TestCreateApplicant(t *testing.T) {
 			name: "Database error",
 			inputJSON: `{
                 "name": "John Doe",
+                "employment_status": "employed",
+                "sex": "male",
                 "date_of_birth": "1990-01-01"
             }`,
 			expectedCode:  http.StatusInternalServerError,
@@ -135,6 +259,68 @@ func TestCreateApplicant(t *testing.T) {
 	}
 }
 
+func TestCreateApplicantDefaults(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	req, _ := http.NewRequest("POST", "/api/applicants", strings.NewReader(`{"name": "John Doe", "employment_status": "employed", "sex": "male", "date_of_birth": "1990-01-01"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var applicant models.Applicant
+	db.First(&applicant, "name = ?", "John Doe")
+	assert.Equal(t, "single", applicant.MaritalStatus)
+	assert.Equal(t, "none", applicant.DisabilityStatus)
+	assert.Equal(t, models.IncomeMonthly, applicant.IncomePeriod)
+}
+
+func TestApplicantNationalID(t *testing.T) {
+	router := setupRouter()
+	setupTestDB(t)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/applicants", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create(`{
+        "name": "John Doe",
+        "employment_status": "employed",
+        "sex": "male",
+        "national_id": "s1234567d",
+        "date_of_birth": "1990-01-01",
+        "household": [
+            {"name": "Jane Doe", "national_id": "T1234567J", "relation": "spouse", "date_of_birth": "1992-01-01", "employment_status": "employed"}
+        ]
+    }`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The same person cannot be registered twice, even under another name.
+	w = create(`{"name": "Jon Doe", "national_id": "S1234567D", "employment_status": "employed", "sex": "male", "date_of_birth": "1990-01-01"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "national ID is already registered to another applicant")
+
+	req, _ := http.NewRequest("GET", "/api/applicants", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Items []models.ApplicantResponse `json:"items"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	if assert.Len(t, response.Items, 1) && assert.Len(t, response.Items[0].Household, 1) {
+		assert.Equal(t, "S****567D", response.Items[0].NationalID)
+		assert.Equal(t, "T****567J", *response.Items[0].Household[0].NationalID)
+	}
+}
+
 func TestGetAllApplicants(t *testing.T) {
 	router := setupRouter()
 
@@ -163,7 +349,7 @@ func TestGetAllApplicants(t *testing.T) {
 						EmploymentStatus: "employed",
 						Sex:              "male",
 						DateOfBirth:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
-						Income:           50000,
+						Income:           models.NewMoney(50000),
 						MaritalStatus:    "single",
 						DisabilityStatus: "none",
 						NumberOfChildren: 0,
@@ -173,7 +359,7 @@ func TestGetAllApplicants(t *testing.T) {
 						EmploymentStatus: "unemployed",
 						Sex:              "female",
 						DateOfBirth:      time.Date(1992, 1, 1, 0, 0, 0, 0, time.UTC),
-						Income:           0,
+						Income:           models.NewMoney(0),
 						MaritalStatus:    "married",
 						DisabilityStatus: "none",
 						NumberOfChildren: 1,
@@ -196,10 +382,132 @@ func TestGetAllApplicants(t *testing.T) {
 
 			assert.Equal(t, tt.expectedCode, w.Code)
 
-			var response []models.ApplicantResponse
+			var response struct {
+				Items []models.ApplicantResponse `json:"items"`
+				Total int64                      `json:"total"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCount, len(response.Items))
+			assert.Equal(t, int64(tt.expectedCount), response.Total)
+		})
+	}
+}
+
+func TestSearchApplicants(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	applicants := []models.Applicant{
+		{
+			Name: "Mary Tan", EmploymentStatus: "unemployed", Sex: "female",
+			DateOfBirth: time.Date(1985, 3, 14, 0, 0, 0, 0, time.UTC),
+			Household: []models.HouseholdMember{
+				{Name: "Gwen Lim", Relation: "daughter", DateOfBirth: time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "primary_school"},
+			},
+		},
+		{
+			Name: "Marie Tang", EmploymentStatus: "employed", Sex: "female",
+			DateOfBirth: time.Date(1990, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name: "Ahmad Ismail", EmploymentStatus: "employed", Sex: "male",
+			DateOfBirth: time.Date(1985, 3, 14, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	db.Create(&applicants)
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedNames []string
+		expectedError string
+	}{
+		{
+			name:          "Fuzzy name ranked by similarity",
+			query:         "?name=mary+tan",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"Mary Tan", "Marie Tang"},
+		},
+		{
+			name:          "Partial name",
+			query:         "?name=ism",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"Ahmad Ismail"},
+		},
+		{
+			name:          "Date of birth",
+			query:         "?date_of_birth=1985-03-14&sort=name",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"Ahmad Ismail", "Mary Tan"},
+		},
+		{
+			name:          "Household member name",
+			query:         "?member=gwen",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"Mary Tan"},
+		},
+		{
+			name:          "Combined terms",
+			query:         "?name=mari&date_of_birth=1990-07-01",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{"Marie Tang"},
+		},
+		{
+			name:          "Wildcard in name matches literally",
+			query:         "?name=_",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{},
+		},
+		{
+			name:          "Wildcard in member name matches literally",
+			query:         "?member=%25",
+			expectedCode:  http.StatusOK,
+			expectedNames: []string{},
+		},
+		{
+			name:          "No terms",
+			query:         "",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "at least one of name, date_of_birth or member is required",
+		},
+		{
+			name:          "Invalid date of birth",
+			query:         "?date_of_birth=14/03/1985",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "invalid date_of_birth",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/applicants/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				return
+			}
+
+			var response struct {
+				Items []struct {
+					Name      string                   `json:"name"`
+					Household []models.HouseholdMember `json:"household"`
+				} `json:"items"`
+				Total int64 `json:"total"`
+			}
 			err := json.Unmarshal(w.Body.Bytes(), &response)
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expectedCount, len(response))
+
+			names := make([]string, 0, len(response.Items))
+			for _, item := range response.Items {
+				names = append(names, item.Name)
+			}
+			assert.Equal(t, tt.expectedNames, names)
+			assert.Equal(t, int64(len(tt.expectedNames)), response.Total)
 		})
 	}
 }
@@ -331,6 +639,40 @@ func TestUpdateApplicant(t *testing.T) {
 			expectedCode:  http.StatusBadRequest,
 			expectedError: "json: cannot unmarshal number into Go struct field updateApplicantInput.name of type string",
 		},
+		{
+			name: "Blank controlled values",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name:             "John Doe",
+					EmploymentStatus: "employed",
+					Sex:              "male",
+					DateOfBirth:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+				db.Create(&applicant)
+				return applicant.ID.String()
+			},
+			inputJSON:     `{"employment_status": "", "marital_status": ""}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `"details":[{"path":"employment_status","message":"is required"},{"path":"marital_status","message":"is required"}]`,
+		},
+		{
+			name: "Invalid values",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name:             "John Doe",
+					EmploymentStatus: "employed",
+					Sex:              "male",
+					DateOfBirth:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+				db.Create(&applicant)
+				return applicant.ID.String()
+			},
+			inputJSON:     `{"marital_status": "complicated", "date_of_birth": "", "income": -1}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `"details":[{"path":"date_of_birth","message":"is required"},{"path":"marital_status","message":"unknown marital_status \"complicated\", expected one of single, married, divorced, separated, widowed"},{"path":"income","message":"must not be negative"}]`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -339,6 +681,7 @@ func TestUpdateApplicant(t *testing.T) {
 
 			req, _ := http.NewRequest("PUT", "/api/applicants/"+applicantID, strings.NewReader(tt.inputJSON))
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", `"1"`)
 
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
@@ -353,6 +696,202 @@ func TestUpdateApplicant(t *testing.T) {
 	}
 }
 
+func TestUpdateApplicantIsAtomic(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	applicant := models.Applicant{
+		Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+		DateOfBirth: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	db.Create(&applicant)
+	for _, name := range []string{"Jack Doe", "Jill Doe"} {
+		db.Create(&models.HouseholdMember{
+			ApplicantID: applicant.ID, Name: name, Relation: "child",
+			DateOfBirth: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "primary_school",
+		})
+	}
+
+	// The bad date of birth on the third member is found before anything is written.
+	body := `{"name": "John Updated", "household": [
+		{"name": "Jack Doe", "relation": "son", "date_of_birth": "2015-01-01", "employment_status": "primary_school"},
+		{"name": "Jill Doe", "relation": "daughter", "date_of_birth": "2015-01-01", "employment_status": "primary_school"},
+		{"name": "Jim Doe", "relation": "son", "date_of_birth": "2015-13-01", "employment_status": "primary_school"}
+	]}`
+	req, _ := http.NewRequest("PUT", "/api/applicants/"+applicant.ID.String(), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"path":"household[2].date_of_birth","message":"must be a date in YYYY-MM-DD format"}`)
+
+	var stored models.Applicant
+	db.Preload("Household").First(&stored, "id = ?", applicant.ID)
+	assert.Equal(t, "John Doe", stored.Name)
+	assert.Equal(t, 1, stored.Version)
+	assert.Len(t, stored.Household, 2)
+}
+
+func TestUpdateApplicantVersion(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	applicant := models.Applicant{
+		Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+		DateOfBirth: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	db.Create(&applicant)
+
+	req, _ := http.NewRequest("GET", "/api/applicants/"+applicant.ID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	update := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/api/applicants/"+applicant.ID.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The first caseworker's change moves the applicant to version 2.
+	w = update(`{"employment_status": "unemployed"}`, etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// The second caseworker still has version 1 and must reload first.
+	w = update(`{"employment_status": "retired"}`, etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Contains(t, w.Body.String(), `"version":2`)
+	w = update(`{"employment_status": "retired", "version": 1}`, "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = update(`{"employment_status": "retired"}`, "abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A change that does not say which version it was made to is refused.
+	w = update(`{"employment_status": "retired"}`, "")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	// Household changes also move the applicant to a new version.
+	req, _ = http.NewRequest("POST", "/api/applicants/"+applicant.ID.String()+"/household",
+		strings.NewReader(`{"name": "Jane Doe", "relation": "spouse", "date_of_birth": "1982-01-01", "employment_status": "employed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	var stored models.Applicant
+	db.First(&stored, "id = ?", applicant.ID)
+	assert.Equal(t, "unemployed", stored.EmploymentStatus)
+	assert.Equal(t, 3, stored.Version)
+}
+
+func TestUpdateApplicantFlagsIneligibleApplications(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	applicant := models.Applicant{
+		Name: "Jane Doe", EmploymentStatus: "unemployed", Sex: "female",
+		DateOfBirth: time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	db.Create(&applicant)
+
+	scheme := models.Scheme{
+		Name: "Retrenchment Assistance",
+		Criteria: models.Criteria{Rules: []models.Rule{
+			{Field: "employment_status", Operator: "==", Value: "unemployed"},
+		}},
+		Version: 1,
+	}
+	db.Create(&scheme)
+	db.Create(&models.SchemeVersion{SchemeID: scheme.ID, Version: 1, Criteria: scheme.Criteria})
+
+	open := models.Application{ApplicantID: applicant.ID, SchemeID: scheme.ID, SchemeVersion: 1, Status: models.ApplicationUnderReview}
+	db.Create(&open)
+	closed := models.Application{ApplicantID: applicant.ID, SchemeID: scheme.ID, SchemeVersion: 1, Status: models.ApplicationDisbursed}
+	db.Create(&closed)
+
+	etag := `"1"`
+	update := func(body string) []uuid.UUID {
+		req, _ := http.NewRequest("PUT", "/api/applicants/"+applicant.ID.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag = w.Header().Get("ETag")
+
+		var response struct {
+			IneligibleApplications []uuid.UUID `json:"ineligible_applications"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response.IneligibleApplications
+	}
+
+	assert.Equal(t, []uuid.UUID{open.ID}, update(`{"employment_status": "employed"}`))
+
+	var flagged models.Application
+	db.First(&flagged, "id = ?", open.ID)
+	assert.NotNil(t, flagged.IneligibleSince)
+
+	var untouched models.Application
+	db.First(&untouched, "id = ?", closed.ID)
+	assert.Nil(t, untouched.IneligibleSince)
+
+	// Qualifying again clears the flag.
+	assert.Empty(t, update(`{"employment_status": "unemployed"}`))
+	db.First(&flagged, "id = ?", open.ID)
+	assert.Nil(t, flagged.IneligibleSince)
+}
+
+func TestUpdateApplicantSkipsLegacyBenefits(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	applicant := models.Applicant{
+		Name: "Jane Doe", EmploymentStatus: "unemployed", Sex: "female",
+		DateOfBirth: time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	db.Create(&applicant)
+
+	scheme := models.Scheme{
+		Name: "Legacy Assistance",
+		Criteria: models.Criteria{Rules: []models.Rule{
+			{Field: "employment_status", Operator: "==", Value: "unemployed"},
+		}},
+		Version: 1,
+	}
+	db.Create(&scheme)
+	db.Create(&models.SchemeVersion{SchemeID: scheme.ID, Version: 1, Criteria: scheme.Criteria})
+	// Benefits were free-form JSON before they were structured.
+	db.Exec(`UPDATE scheme_versions SET benefits = '{"amount": "lots"}' WHERE scheme_id = ?`, scheme.ID)
+
+	open := models.Application{ApplicantID: applicant.ID, SchemeID: scheme.ID, SchemeVersion: 1, Status: models.ApplicationUnderReview}
+	db.Create(&open)
+
+	req, _ := http.NewRequest("PUT", "/api/applicants/"+applicant.ID.String(), strings.NewReader(`{"employment_status": "employed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var unchanged models.Application
+	db.First(&unchanged, "id = ?", open.ID)
+	assert.Nil(t, unchanged.IneligibleSince)
+}
+
 func TestDeleteApplicant(t *testing.T) {
 	router := setupRouter()
 
@@ -386,6 +925,25 @@ func TestDeleteApplicant(t *testing.T) {
 			expectedCode:  http.StatusNotFound,
 			expectedError: "applicant not found",
 		},
+		{
+			name: "Applicant with application history",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name:             "John Doe",
+					EmploymentStatus: "employed",
+					Sex:              "male",
+					DateOfBirth:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+				db.Create(&applicant)
+				application := models.Application{ApplicantID: applicant.ID, SchemeID: uuid.New(), SchemeVersion: 1, Status: models.ApplicationWithdrawn}
+				db.Create(&application)
+				db.Create(&models.ApplicationStatusChange{ApplicationID: application.ID, ToStatus: models.ApplicationSubmitted, ChangedBy: uuid.NewString()})
+				return applicant.ID.String()
+			},
+			expectedCode:  http.StatusConflict,
+			expectedError: "applicant has applications with status history",
+		},
 	}
 
 	for _, tt := range tests {
@@ -405,3 +963,269 @@ func TestDeleteApplicant(t *testing.T) {
 		})
 	}
 }
+
+func TestDetectAndMergeDuplicateApplicants(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	dob := time.Date(1985, 3, 14, 0, 0, 0, 0, time.UTC)
+	child := models.HouseholdMember{Name: "Gwen Tan", Relation: "daughter", DateOfBirth: time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "primary_school"}
+	survivor := models.Applicant{Name: "Mary Tan", EmploymentStatus: "unemployed", Sex: "female", DateOfBirth: dob, Household: []models.HouseholdMember{child}}
+	nationalID := "S1234567D"
+	duplicate := models.Applicant{
+		Name: "Mary Tan Mei Ling", NationalID: &nationalID, EmploymentStatus: "unemployed", Sex: "female", DateOfBirth: dob,
+		Household: []models.HouseholdMember{
+			child,
+			{Name: "Ken Tan", Relation: "son", DateOfBirth: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "preschool"},
+		},
+	}
+	unrelated := models.Applicant{Name: "Ahmad Ismail", EmploymentStatus: "employed", Sex: "male", DateOfBirth: dob}
+	db.Create(&survivor)
+	db.Create(&duplicate)
+	db.Create(&unrelated)
+
+	schemeID := uuid.New()
+	db.Create(&models.Application{ApplicantID: duplicate.ID, SchemeID: schemeID, Status: models.ApplicationSubmitted})
+
+	req, _ := http.NewRequest("POST", "/api/applicants/duplicates/detect", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"suspected_duplicates":1`)
+
+	req, _ = http.NewRequest("GET", "/api/applicants/duplicates", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var duplicates struct {
+		Items []struct {
+			Applicant models.ApplicantResponse `json:"applicant"`
+			Duplicate models.ApplicantResponse `json:"duplicate"`
+		} `json:"items"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &duplicates)
+	assert.NoError(t, err)
+	if assert.Len(t, duplicates.Items, 1) {
+		names := []string{duplicates.Items[0].Applicant.Name, duplicates.Items[0].Duplicate.Name}
+		assert.ElementsMatch(t, []string{"Mary Tan", "Mary Tan Mei Ling"}, names)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/applicants/"+survivor.ID.String()+"/merge", strings.NewReader(`{"duplicate_id": "`+duplicate.ID.String()+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"applications_moved":1`)
+	assert.Contains(t, w.Body.String(), `"household_moved":1`)
+
+	var merged models.Applicant
+	err = db.Preload("Household").First(&merged, "id = ?", survivor.ID).Error
+	assert.NoError(t, err)
+	assert.Len(t, merged.Household, 2)
+	if assert.NotNil(t, merged.NationalID) {
+		assert.Equal(t, nationalID, *merged.NationalID)
+	}
+
+	var applications []models.Application
+	db.Where("applicant_id = ?", survivor.ID).Find(&applications)
+	assert.Len(t, applications, 1)
+
+	var remaining int64
+	db.Model(&models.Applicant{}).Where("id = ?", duplicate.ID).Count(&remaining)
+	assert.Equal(t, int64(0), remaining)
+
+	var merges []models.ApplicantMerge
+	db.Find(&merges)
+	if assert.Len(t, merges, 1) {
+		assert.Equal(t, duplicate.ID, merges[0].MergedID)
+		assert.Contains(t, string(merges[0].MergedApplicant), "Mary Tan Mei Ling")
+	}
+
+	// Merging an applicant that no longer exists fails.
+	req, _ = http.NewRequest("POST", "/api/applicants/"+survivor.ID.String()+"/merge", strings.NewReader(`{"duplicate_id": "`+duplicate.ID.String()+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMergeApplicantsConflict(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	dob := time.Date(1985, 3, 14, 0, 0, 0, 0, time.UTC)
+	survivor := models.Applicant{Name: "Mary Tan", EmploymentStatus: "unemployed", Sex: "female", DateOfBirth: dob}
+	duplicate := models.Applicant{Name: "Mary Tan", EmploymentStatus: "unemployed", Sex: "female", DateOfBirth: dob}
+	db.Create(&survivor)
+	db.Create(&duplicate)
+
+	schemeID := uuid.New()
+	db.Create(&models.Application{ApplicantID: survivor.ID, SchemeID: schemeID, Status: models.ApplicationUnderReview})
+	db.Create(&models.Application{ApplicantID: duplicate.ID, SchemeID: schemeID, Status: models.ApplicationSubmitted})
+
+	req, _ := http.NewRequest("POST", "/api/applicants/"+survivor.ID.String()+"/merge", strings.NewReader(`{"duplicate_id": "`+duplicate.ID.String()+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), schemeID.String())
+
+	var remaining int64
+	db.Model(&models.Applicant{}).Where("id = ?", duplicate.ID).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+}
+
+func TestMergeApplicantsRelationLimits(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	dob := time.Date(1985, 3, 14, 0, 0, 0, 0, time.UTC)
+	survivor := models.Applicant{Name: "Mary Tan", EmploymentStatus: "unemployed", Sex: "female", DateOfBirth: dob}
+	duplicate := models.Applicant{Name: "Mary Tan", EmploymentStatus: "unemployed", Sex: "female", DateOfBirth: dob}
+	db.Create(&survivor)
+	db.Create(&duplicate)
+	db.Create(&models.HouseholdMember{
+		ApplicantID: survivor.ID, Name: "Tom Tan", Relation: "spouse",
+		DateOfBirth: time.Date(1983, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "employed",
+	})
+	db.Create(&models.HouseholdMember{
+		ApplicantID: duplicate.ID, Name: "Tim Lee", Relation: "spouse",
+		DateOfBirth: time.Date(1984, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "employed",
+	})
+
+	req, _ := http.NewRequest("POST", "/api/applicants/"+survivor.ID.String()+"/merge", strings.NewReader(`{"duplicate_id": "`+duplicate.ID.String()+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"path":"household[1].relation","message":"a household may have at most 1 spouse"}`)
+
+	var remaining int64
+	db.Model(&models.Applicant{}).Where("id = ?", duplicate.ID).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+}
+
+func TestHouseholdMembers(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	applicant := models.Applicant{
+		Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+		DateOfBirth: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	db.Create(&applicant)
+	son := models.HouseholdMember{
+		ApplicantID: applicant.ID, Name: "Jack Doe", Relation: "son",
+		DateOfBirth: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "primary_school",
+	}
+	db.Create(&son)
+
+	// Each change is made to the version the previous one produced.
+	etag := `"1"`
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/api/applicants/"+applicant.ID.String()+"/household"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK && w.Header().Get("ETag") != "" {
+			etag = w.Header().Get("ETag")
+		}
+		return w
+	}
+
+	req, _ := http.NewRequest("DELETE", "/api/applicants/"+applicant.ID.String()+"/household/"+son.ID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = send("POST", "", `{"name": "Jane Doe", "relation": "spouse", "date_of_birth": "1982-01-01", "employment_status": "employed"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var added struct {
+		Member models.HouseholdMember `json:"member"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &added)
+	assert.NotEqual(t, uuid.Nil, added.Member.ID)
+
+	// A household has at most one spouse.
+	w = send("POST", "", `{"name": "Joan Doe", "relation": "spouse", "date_of_birth": "1983-01-01", "employment_status": "employed"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"path":"relation","message":"a household may have at most 1 spouse"}`)
+	w = send("PUT", "/"+son.ID.String(), `{"relation": "spouse"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"path":"relation","message":"a household may have at most 1 spouse"}`)
+
+	// Updating a member keeps its ID and the other members.
+	w = send("PUT", "/"+son.ID.String(), `{"employment_status": "secondary_school"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.HouseholdMember
+	db.First(&updated, "id = ?", son.ID)
+	assert.Equal(t, "secondary_school", updated.EmploymentStatus)
+	assert.Equal(t, "Jack Doe", updated.Name)
+	assert.Equal(t, son.CreatedAt.Unix(), updated.CreatedAt.Unix())
+
+	w = send("PUT", "/"+uuid.NewString(), `{"name": "Nobody"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "household member not found")
+
+	w = send("DELETE", "/"+added.Member.ID.String(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("DELETE", "/"+added.Member.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = send("GET", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page struct {
+		Items []models.HouseholdMember `json:"items"`
+		Total int64                    `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, son.ID, page.Items[0].ID)
+}
+
+func TestApplicantHouseholdIncome(t *testing.T) {
+	router := setupRouter()
+	setupTestDB(t)
+
+	body := `{
+        "name": "John Doe",
+        "employment_status": "employed",
+        "sex": "male",
+        "date_of_birth": "1980-01-01",
+        "income": 200000,
+        "household": [
+            {"name": "Jane Doe", "relation": "spouse", "date_of_birth": "1982-01-01", "employment_status": "employed", "income": 2400000, "income_period": "annual"},
+            {"name": "Jack Doe", "relation": "son", "date_of_birth": "2015-01-01", "employment_status": "primary_school"}
+        ]
+    }`
+	req, _ := http.NewRequest("POST", "/api/applicants", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/applicants", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var page struct {
+		Items []models.ApplicantResponse `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, models.IncomeMonthly, page.Items[0].IncomePeriod)
+	assert.Equal(t, models.NewMoney(400000), page.Items[0].HouseholdIncome)
+	assert.Equal(t, models.NewMoney(133333), page.Items[0].PerCapitaIncome)
+
+	req, _ = http.NewRequest("POST", "/api/applicants", strings.NewReader(`{"name": "Jim Doe", "employment_status": "employed", "sex": "male", "date_of_birth": "1980-01-01", "income_period": "weekly"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"path":"income_period","message":"unknown income_period \"weekly\", expected one of monthly, annual"}`)
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bensiauu/financial-assistance-scheme/internal/middleware"
+	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// invalidApplicantError reports validation errors that can only be found once
+// the applicant's stored household has been read.
+type invalidApplicantError []utils.CriteriaError
+
+func (e invalidApplicantError) Error() string {
+	return "invalid applicant"
+}
+
+var errMemberNotFound = errors.New("household member not found")
+
+type updateHouseholdMemberInput struct {
+	Name             *string       `json:"name,omitempty"`
+	NationalID       *string       `json:"national_id,omitempty"`
+	Relation         *string       `json:"relation,omitempty"`
+	DateOfBirth      *string       `json:"date_of_birth,omitempty"`
+	EmploymentStatus *string       `json:"employment_status,omitempty"`
+	Income           *models.Money `json:"income,omitempty"`
+	IncomePeriod     *string       `json:"income_period,omitempty"`
+}
+
+var householdListOptions = utils.ListOptions{
+	Filters:     map[string]utils.FilterKind{"relation": utils.StringFilter},
+	Sorts:       []string{"name", "date_of_birth", "created_at"},
+	DefaultSort: "created_at",
+}
+
+// newHouseholdMemberResponse masks the member's national ID unless
+// revealNationalIDs is set.
+func newHouseholdMemberResponse(member models.HouseholdMember, revealNationalIDs bool) models.HouseholdMember {
+	if member.NationalID != nil && !revealNationalIDs {
+		masked := utils.MaskNationalID(*member.NationalID)
+		member.NationalID = &masked
+	}
+	return member
+}
+
+func GetHousehold(c *gin.Context) {
+	applicantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	}
+
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), householdListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.DB.Select("id").First(&models.Applicant{}, "id = ?", applicantID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var members []models.HouseholdMember
+	page, err := query.Find(db.DB.Where("applicant_id = ?", applicantID), &members)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]models.HouseholdMember, 0, len(members))
+	for _, member := range members {
+		response = append(response, newHouseholdMemberResponse(member, middleware.CanViewNationalIDs(c)))
+	}
+
+	page.Items = response
+	c.JSON(http.StatusOK, page)
+}
+
+func AddHouseholdMember(c *gin.Context) {
+	applicantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	}
+
+	var input householdMemberInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expected, err := expectedVersion(c, nil)
+	if err != nil {
+		respondVersionError(c, err)
+		return
+	}
+
+	v := utils.NewValidator()
+	member := validateHouseholdMember(v, "", input)
+	if !v.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid applicant", "details": v.Errors})
+		return
+	}
+
+	var version int
+	var flagged []uuid.UUID
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		applicant, others, err := lockHousehold(tx, applicantID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(applicant, expected); err != nil {
+			return err
+		}
+
+		member.ApplicantID = applicant.ID
+		member.NationalID = parseMemberNationalID(v, input.NationalID, applicant, others)
+		checkRelationLimits(v, append(others, member), func(int) string { return "relation" })
+		if !v.Valid() {
+			return invalidApplicantError(v.Errors)
+		}
+
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, &applicant); err != nil {
+			return err
+		}
+		version = applicant.Version
+
+		flagged, err = utils.FlagIneligibleApplications(tx, applicant.ID, utils.Clock())
+		return err
+	})
+	if err != nil {
+		respondApplicantError(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(version))
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "household member added successfully",
+		"member":                  newHouseholdMemberResponse(member, middleware.CanViewNationalIDs(c)),
+		"version":                 version,
+		"ineligible_applications": flagged,
+	})
+}
+
+func UpdateHouseholdMember(c *gin.Context) {
+	applicantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("memberId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errMemberNotFound.Error()})
+		return
+	}
+
+	var input updateHouseholdMemberInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expected, err := expectedVersion(c, nil)
+	if err != nil {
+		respondVersionError(c, err)
+		return
+	}
+
+	var member models.HouseholdMember
+	var version int
+	var flagged []uuid.UUID
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		applicant, household, err := lockHousehold(tx, applicantID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(applicant, expected); err != nil {
+			return err
+		}
+
+		var others []models.HouseholdMember
+		found := false
+		for _, m := range household {
+			if m.ID == memberID {
+				member, found = m, true
+				continue
+			}
+			others = append(others, m)
+		}
+		if !found {
+			return errMemberNotFound
+		}
+
+		// Validate the member as it will be after the update.
+		merged := householdMemberInput{
+			Name:             member.Name,
+			Relation:         member.Relation,
+			DateOfBirth:      member.DateOfBirth.Format("2006-01-02"),
+			EmploymentStatus: member.EmploymentStatus,
+			Income:           member.Income,
+			IncomePeriod:     member.IncomePeriod,
+		}
+		if member.NationalID != nil {
+			merged.NationalID = *member.NationalID
+		}
+		if input.Name != nil {
+			merged.Name = *input.Name
+		}
+		if input.NationalID != nil {
+			merged.NationalID = *input.NationalID
+		}
+		if input.Relation != nil {
+			merged.Relation = *input.Relation
+		}
+		if input.DateOfBirth != nil {
+			merged.DateOfBirth = *input.DateOfBirth
+		}
+		if input.EmploymentStatus != nil {
+			merged.EmploymentStatus = *input.EmploymentStatus
+		}
+		if input.Income != nil {
+			merged.Income = *input.Income
+		}
+		if input.IncomePeriod != nil {
+			merged.IncomePeriod = *input.IncomePeriod
+		}
+
+		v := utils.NewValidator()
+		updated := validateHouseholdMember(v, "", merged)
+		updated.NationalID = parseMemberNationalID(v, merged.NationalID, applicant, others)
+		checkRelationLimits(v, append(others, updated), func(int) string { return "relation" })
+		if !v.Valid() {
+			return invalidApplicantError(v.Errors)
+		}
+
+		member.Name = updated.Name
+		member.NationalID = updated.NationalID
+		member.Relation = updated.Relation
+		member.DateOfBirth = updated.DateOfBirth
+		member.EmploymentStatus = updated.EmploymentStatus
+		member.Income = updated.Income
+		member.IncomePeriod = updated.IncomePeriod
+		err = tx.Model(&member).
+			Select("name", "national_id", "relation", "date_of_birth", "employment_status", "income", "income_period", "updated_at").
+			Updates(&member).Error
+		if err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, &applicant); err != nil {
+			return err
+		}
+		version = applicant.Version
+
+		flagged, err = utils.FlagIneligibleApplications(tx, applicant.ID, utils.Clock())
+		return err
+	})
+	if err != nil {
+		respondApplicantError(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(version))
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "household member updated successfully",
+		"member":                  newHouseholdMemberResponse(member, middleware.CanViewNationalIDs(c)),
+		"version":                 version,
+		"ineligible_applications": flagged,
+	})
+}
+
+func RemoveHouseholdMember(c *gin.Context) {
+	applicantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("memberId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errMemberNotFound.Error()})
+		return
+	}
+
+	expected, err := expectedVersion(c, nil)
+	if err != nil {
+		respondVersionError(c, err)
+		return
+	}
+
+	var applicant models.Applicant
+	var flagged []uuid.UUID
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		applicant, _, err = lockHousehold(tx, applicantID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(applicant, expected); err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ? AND applicant_id = ?", memberID, applicantID).Delete(&models.HouseholdMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errMemberNotFound
+		}
+		if err := bumpVersion(tx, &applicant); err != nil {
+			return err
+		}
+
+		flagged, err = utils.FlagIneligibleApplications(tx, applicant.ID, utils.Clock())
+		return err
+	})
+	if err != nil {
+		respondApplicantError(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(applicant.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "household member removed successfully",
+		"version":                 applicant.Version,
+		"ineligible_applications": flagged,
+	})
+}
+
+// lockHousehold locks the applicant so concurrent changes to their household
+// are checked one at a time, and returns the applicant and their household.
+func lockHousehold(tx *gorm.DB, applicantID uuid.UUID) (models.Applicant, []models.HouseholdMember, error) {
+	var applicant models.Applicant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&applicant, "id = ?", applicantID).Error
+	if err != nil {
+		return models.Applicant{}, nil, err
+	}
+
+	var household []models.HouseholdMember
+	if err := tx.Where("applicant_id = ?", applicantID).Order("created_at, id").Find(&household).Error; err != nil {
+		return models.Applicant{}, nil, err
+	}
+	return applicant, household, nil
+}
+
+// parseMemberNationalID normalizes and validates a household member's
+// national ID, which must differ from the applicant's and the other members'.
+func parseMemberNationalID(v *utils.Validator, raw string, applicant models.Applicant, others []models.HouseholdMember) *string {
+	id := utils.NormalizeNationalID(raw)
+	if id == "" {
+		return nil
+	}
+	if err := utils.ValidateNationalID(id); err != nil {
+		v.Fail("national_id", err.Error())
+		return nil
+	}
+
+	used := applicant.NationalID != nil && *applicant.NationalID == id
+	for _, member := range others {
+		if member.NationalID != nil && *member.NationalID == id {
+			used = true
+		}
+	}
+	if used {
+		v.Fail("national_id", "is used more than once in the household")
+		return nil
+	}
+	return &id
+}
+
+func respondApplicantError(c *gin.Context, err error) {
+	var invalidErr invalidApplicantError
+	var conflictErr *versionConflictError
+	switch {
+	case errors.As(err, &invalidErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid applicant", "details": invalidErr})
+	case errors.As(err, &conflictErr):
+		c.Header("ETag", versionETag(conflictErr.Current))
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": conflictErr.Error(), "version": conflictErr.Current})
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "applicant not found"})
+	case err == errMemberNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case isUniqueViolation(err):
+		c.JSON(http.StatusConflict, gin.H{"error": "national ID is already registered to another applicant"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
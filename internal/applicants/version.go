@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// versionConflictError blocks a change made to an outdated copy of the
+// applicant, so one caseworker cannot silently overwrite another's edits.
+type versionConflictError struct {
+	Current int
+}
+
+func (e *versionConflictError) Error() string {
+	return "applicant has been modified since it was read"
+}
+
+// errVersionRequired rejects a change that does not say which version of the
+// applicant it was made to.
+var errVersionRequired = errors.New("If-Match or version is required")
+
+// versionETag is the ETag of an applicant version.
+func versionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// expectedVersion returns the applicant version a change was made to, from
+// the If-Match header or the version field of the body. It returns
+// errVersionRequired when the client gave neither.
+func expectedVersion(c *gin.Context, bodyVersion *int) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if bodyVersion == nil {
+			return 0, errVersionRequired
+		}
+		return *bodyVersion, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil {
+		return 0, fmt.Errorf("If-Match must be the ETag of an applicant")
+	}
+	if bodyVersion != nil && *bodyVersion != version {
+		return 0, fmt.Errorf("If-Match and version refer to different versions")
+	}
+	return version, nil
+}
+
+// respondVersionError reports a missing or malformed precondition.
+func respondVersionError(c *gin.Context, err error) {
+	if err == errVersionRequired {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// checkVersion returns a versionConflictError unless the locked applicant is
+// still at the expected version.
+func checkVersion(applicant models.Applicant, expected int) error {
+	if expected != applicant.Version {
+		return &versionConflictError{Current: applicant.Version}
+	}
+	return nil
+}
+
+// bumpVersion records a change to the applicant or their household.
+func bumpVersion(tx *gorm.DB, applicant *models.Applicant) error {
+	if err := tx.Model(applicant).UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+		return err
+	}
+	applicant.Version++
+	return nil
+}
@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/bensiauu/financial-assistance-scheme/internal/middleware"
 	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
 	"github.com/bensiauu/financial-assistance-scheme/models"
 	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateApplication(c *gin.Context) {
@@ -17,8 +25,19 @@ func CreateApplication(c *gin.Context) {
 		return
 	}
 
+	if application.Status == "" {
+		application.Status = models.ApplicationSubmitted
+	}
+	if !contains(initialStatuses, application.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid initial status", "allowed": initialStatuses})
+		return
+	}
+	application.StatusReason = ""
+	application.Entitlement = nil
+	application.IneligibleSince = nil
+
 	// Check eligibility using the shared utility function
-	eligibleSchemes, err := utils.GetEligibleSchemes(application.ApplicantID.String())
+	eligibleSchemes, _, err := utils.GetEligibleSchemes(application.ApplicantID.String(), utils.Clock())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -28,6 +47,8 @@ func CreateApplication(c *gin.Context) {
 	for _, scheme := range eligibleSchemes {
 		if scheme.ID == application.SchemeID {
 			isEligible = true
+			// Pin the application to the version it was evaluated against
+			application.SchemeVersion = scheme.Version
 			break
 		}
 	}
@@ -37,26 +58,64 @@ func CreateApplication(c *gin.Context) {
 		return
 	}
 
-	if err := db.DB.Create(&application).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application record in DB"})
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkNoActiveApplication(tx, application); err != nil {
+			return err
+		}
+		if err := checkSchemeAccepting(tx, application.SchemeID); err != nil {
+			return err
+		}
+		if err := tx.Create(&application).Error; err != nil {
+			return err
+		}
+		return recordStatusChange(tx, c, application.ID, "", application.Status, "")
+	})
+	err = activeApplicationConflict(err, application)
+	if err != nil {
+		var duplicateErr *duplicateError
+		var capacityErr *capacityError
+		switch {
+		case errors.Is(err, errSchemeClosed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.As(err, &duplicateErr):
+			c.JSON(http.StatusConflict, gin.H{"error": duplicateErr.Error(), "application_id": duplicateErr.ExistingID})
+		case errors.As(err, &capacityErr):
+			c.JSON(http.StatusConflict, gin.H{"error": capacityErr.Error(), "capacity": capacityErr.Capacity})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application record in DB"})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Application created successfully"})
 }
+
+var applicationListOptions = utils.ListOptions{
+	Filters: map[string]utils.FilterKind{
+		"status":       utils.StringFilter,
+		"scheme_id":    utils.UUIDFilter,
+		"applicant_id": utils.UUIDFilter,
+	},
+	Sorts:       []string{"created_at", "updated_at", "status"},
+	DefaultSort: "-created_at",
+}
+
 func GetAllApplication(c *gin.Context) {
-	var applications []models.Application
-	if err := db.DB.Find(&applications).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), applicationListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(applications) == 0 {
-		c.JSON(http.StatusOK, []models.Application{})
+	applications := []models.Application{}
+	page, err := query.Find(db.DB, &applications)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, applications)
+	page.Items = applications
+	c.JSON(http.StatusOK, page)
 }
 func GetApplicationByID(c *gin.Context) {
 	id := c.Param("id")
@@ -77,15 +136,10 @@ func GetApplicationByID(c *gin.Context) {
 
 func UpdateApplication(c *gin.Context) {
 	id := c.Param("id")
-	var application models.Application
-
-	if err := db.DB.First(&application, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
-		return
-	}
 
 	type updateInput struct {
-		Status string `json:"status"`
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
 	}
 	var input updateInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -93,32 +147,265 @@ func UpdateApplication(c *gin.Context) {
 		return
 	}
 
-	if err := db.DB.Model(&application).Updates(input).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update application"})
+	if !isKnownStatus(input.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown status " + strconv.Quote(input.Status)})
+		return
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Status == models.ApplicationRejected && input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to reject an application"})
+		return
+	}
+
+	var application models.Application
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the application so concurrent updates cannot both pass the transition check.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&application, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := checkTransition(application.Status, input.Status); err != nil {
+			return err
+		}
+		// An appeal reactivates a rejected application, which must not clash
+		// with an application made since.
+		if !contains(models.ActiveApplicationStatuses, application.Status) && contains(models.ActiveApplicationStatuses, input.Status) {
+			if err := checkNoActiveApplication(tx, application); err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]interface{}{"status": input.Status, "status_reason": input.Reason}
+		if input.Status == models.ApplicationApproved {
+			// The applicant's details may have changed since they applied.
+			eligibility, err := utils.EvaluateApplication(tx, application, utils.Clock())
+			if err != nil {
+				return fmt.Errorf("failed to re-check eligibility: %w", err)
+			}
+			if !eligibility.Eligible {
+				return &ineligibleError{Eligibility: eligibility}
+			}
+			if err := checkSchemeCapacity(tx, application.SchemeID, eligibility.Entitlement.Total); err != nil {
+				return err
+			}
+			updates["entitlement"] = eligibility.Entitlement
+			updates["ineligible_since"] = nil
+		}
+
+		if err := tx.Model(&application).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordStatusChange(tx, c, application.ID, application.Status, input.Status, input.Reason)
+	})
+	err = activeApplicationConflict(err, application)
+	if err != nil {
+		var transitionErr *transitionError
+		var ineligibleErr *ineligibleError
+		var duplicateErr *duplicateError
+		var capacityErr *capacityError
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+		case errors.As(err, &transitionErr):
+			c.JSON(http.StatusConflict, gin.H{"error": transitionErr.Error(), "allowed": transitionErr.Allowed})
+		case errors.As(err, &ineligibleErr):
+			c.JSON(http.StatusForbidden, gin.H{"error": ineligibleErr.Error(), "eligibility": ineligibleErr.Eligibility})
+		case errors.As(err, &duplicateErr):
+			c.JSON(http.StatusConflict, gin.H{"error": duplicateErr.Error(), "application_id": duplicateErr.ExistingID})
+		case errors.As(err, &capacityErr):
+			c.JSON(http.StatusConflict, gin.H{"error": capacityErr.Error(), "capacity": capacityErr.Capacity})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update application"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "application updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "application updated successfully", "status": input.Status})
 }
 
-func DeleteApplication(c *gin.Context) {
+// checkNoActiveApplication returns a duplicateError if the applicant already
+// has another active application for the scheme. It locks the applicant's row
+// so that concurrent requests for the same applicant are checked one at a
+// time; the partial unique index on applications backs this up.
+func checkNoActiveApplication(tx *gorm.DB, application models.Application) error {
+	var applicant models.Applicant
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&applicant, "id = ?", application.ApplicantID).Error; err != nil {
+		return err
+	}
+
+	existing, err := findActiveApplication(tx, application)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &duplicateError{ExistingID: existing.ID}
+}
+
+// activeApplicationConflict turns a violation of the partial unique index,
+// hit when a concurrent request slipped past checkNoActiveApplication, into
+// the same duplicateError. Other errors are returned unchanged.
+func activeApplicationConflict(err error, application models.Application) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" || pgErr.ConstraintName != "idx_applications_active_applicant_scheme" {
+		return err
+	}
+	existing, findErr := findActiveApplication(db.DB, application)
+	if findErr != nil {
+		return err
+	}
+	return &duplicateError{ExistingID: existing.ID}
+}
+
+// findActiveApplication returns the applicant's active application for the
+// scheme other than the given one.
+func findActiveApplication(tx *gorm.DB, application models.Application) (models.Application, error) {
+	query := tx.Where("applicant_id = ? AND scheme_id = ? AND status IN ?", application.ApplicantID, application.SchemeID, models.ActiveApplicationStatuses)
+	if application.ID != uuid.Nil {
+		query = query.Where("id <> ?", application.ID)
+	}
+
+	var existing models.Application
+	err := query.First(&existing).Error
+	return existing, err
+}
+
+// checkSchemeAccepting returns an error unless the scheme is within its
+// application window and has capacity left. The scheme's row is share-locked
+// so that approvals cannot use up its capacity until the transaction ends.
+func checkSchemeAccepting(tx *gorm.DB, schemeID uuid.UUID) error {
+	var scheme models.Scheme
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&scheme, "id = ?", schemeID).Error; err != nil {
+		return err
+	}
+	if !utils.IsSchemeOpen(scheme, utils.Clock()) {
+		return errSchemeClosed
+	}
+
+	capacity, err := utils.GetSchemeCapacity(tx, scheme)
+	if err != nil {
+		return err
+	}
+	if capacity.Full() {
+		return &capacityError{Message: "Scheme has no remaining capacity", Capacity: capacity}
+	}
+	return nil
+}
+
+// checkSchemeCapacity returns a capacityError unless the scheme can take one
+// more beneficiary with the given entitlement. The scheme's row stays locked
+// until the transaction ends so concurrent approvals are checked one at a time.
+func checkSchemeCapacity(tx *gorm.DB, schemeID uuid.UUID, total models.Money) error {
+	var scheme models.Scheme
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&scheme, "id = ?", schemeID).Error; err != nil {
+		return err
+	}
+
+	capacity, err := utils.GetSchemeCapacity(tx, scheme)
+	if err != nil {
+		return err
+	}
+	if !capacity.CanGrant(total) {
+		return &capacityError{Message: "Approval would exceed the scheme's beneficiary limit or budget", Capacity: capacity}
+	}
+	return nil
+}
+
+// recordStatusChange appends a status change made by the authenticated
+// administrator to the application's history.
+func recordStatusChange(tx *gorm.DB, c *gin.Context, applicationID uuid.UUID, from, to, remark string) error {
+	change := models.ApplicationStatusChange{
+		ApplicationID: applicationID,
+		FromStatus:    from,
+		ToStatus:      to,
+		ChangedBy:     middleware.Subject(c),
+		Remark:        remark,
+	}
+	return tx.Create(&change).Error
+}
+
+var applicationHistoryListOptions = utils.ListOptions{
+	Sorts:       []string{"created_at"},
+	DefaultSort: "created_at",
+}
+
+func GetApplicationHistory(c *gin.Context) {
 	id := c.Param("id")
 
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), applicationHistoryListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var application models.Application
 	if err := db.DB.First(&application, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	result := db.DB.Delete(&application)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete application"})
+	history := []models.ApplicationStatusChange{}
+	page, err := query.Find(db.DB.Where("application_id = ?", application.ID), &history)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if result.RowsAffected == 0 {
+	page.Items = history
+	c.JSON(http.StatusOK, page)
+}
+
+func DeleteApplication(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
 		return
 	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the application keeps status changes from being recorded
+		// between the check and the delete.
+		var application models.Application
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&application, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		// The status history is an audit trail, so an application that has one
+		// must be withdrawn rather than deleted.
+		var changeCount int64
+		if err := tx.Model(&models.ApplicationStatusChange{}).Where("application_id = ?", application.ID).Count(&changeCount).Error; err != nil {
+			return err
+		}
+		if changeCount > 0 {
+			return errHasStatusHistory
+		}
+
+		return tx.Delete(&application).Error
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "application not found"})
+		return
+	case errors.Is(err, errHasStatusHistory) || utils.IsStatusHistoryViolation(err):
+		c.JSON(http.StatusConflict, gin.H{"error": errHasStatusHistory.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete application"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "application deleted successfully"})
 }
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
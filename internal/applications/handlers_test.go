@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -27,7 +28,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	testDB.AutoMigrate(&models.Application{}, &models.Applicant{}, &models.Scheme{})
+	testDB.AutoMigrate(&models.Application{}, &models.Applicant{}, &models.HouseholdMember{}, &models.Scheme{}, &models.SchemeVersion{}, &models.ApplicationStatusChange{})
 
 	db.DB = testDB
 
@@ -38,21 +39,32 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		}
 
 		sqlDB.Exec("DROP table IF EXISTS applicants CASCADE")
+		sqlDB.Exec("DROP table IF EXISTS household_members CASCADE")
 		sqlDB.Exec("DROP table IF EXISTS applications CASCADE")
 		sqlDB.Exec("DROP table IF EXISTS schemes CASCADE")
+		sqlDB.Exec("DROP table IF EXISTS scheme_versions CASCADE")
+		sqlDB.Exec("DROP table IF EXISTS application_status_changes CASCADE")
 		sqlDB.Close()
 	})
 
 	return testDB
 }
 
+// testAdminID is the ID of the administrator AuthMiddleware loads for the
+// test requests.
+var testAdminID = uuid.NewString()
+
 func setupRouter() *gin.Engine {
 	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("administrator", models.Administrator{ID: uuid.MustParse(testAdminID), Role: models.RoleCaseworker})
+	})
 	router.POST("/api/applications", handlers.CreateApplication)
 	router.GET("/api/applications", handlers.GetAllApplication)
 	router.GET("/api/applications/:id", handlers.GetApplicationByID)
 	router.PUT("/api/applications/:id", handlers.UpdateApplication)
 	router.DELETE("/api/applications/:id", handlers.DeleteApplication)
+	router.GET("/api/applications/:id/history", handlers.GetApplicationHistory)
 	return router
 }
 
@@ -81,7 +93,7 @@ func TestCreateApplication(t *testing.T) {
 					Criteria: models.Criteria{Rules: []models.Rule{
 						{Field: "income", Operator: "<=", Value: 20000},
 					}},
-					Benefits: json.RawMessage(`{}`),
+					Benefits: models.Benefits{},
 				}
 				db.Create(&scheme)
 
@@ -98,7 +110,7 @@ func TestCreateApplication(t *testing.T) {
 				applicant := models.Applicant{
 					Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
 					DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
-					Income:      50000,
+					Income:      models.NewMoney(50000),
 				}
 				db.Create(&applicant)
 
@@ -107,7 +119,7 @@ func TestCreateApplication(t *testing.T) {
 					Criteria: models.Criteria{Rules: []models.Rule{
 						{Field: "income", Operator: "<=", Value: 20000},
 					}},
-					Benefits: json.RawMessage(`{}`),
+					Benefits: models.Benefits{},
 				}
 				db.Create(&scheme)
 
@@ -117,6 +129,63 @@ func TestCreateApplication(t *testing.T) {
 			expectedCode:  http.StatusForbidden,
 			expectedError: "Applicant is not eligible for this scheme",
 		},
+		{
+			name: "Active application exists",
+			setupFunc: func() (string, string) {
+				db := setupTestDB(t)
+				existing := createTestApplication(t, db, models.ApplicationUnderReview)
+				return existing.ApplicantID.String(), existing.SchemeID.String()
+			},
+			inputJSON:     `{"applicantID": "<APPLICANT_ID>", "schemeID": "<SCHEME_ID>"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: "Applicant already has an active application for this scheme",
+		},
+		{
+			name: "Reapply after rejection",
+			setupFunc: func() (string, string) {
+				db := setupTestDB(t)
+				existing := createTestApplication(t, db, models.ApplicationRejected)
+				return existing.ApplicantID.String(), existing.SchemeID.String()
+			},
+			inputJSON:     `{"applicantID": "<APPLICANT_ID>", "schemeID": "<SCHEME_ID>"}`,
+			expectedCode:  http.StatusOK,
+			expectedError: "",
+		},
+		{
+			name: "Scheme closed",
+			setupFunc: func() (string, string) {
+				db := setupTestDB(t)
+				existing := createTestApplication(t, db, models.ApplicationWithdrawn)
+				db.Model(&models.Scheme{}).Where("id = ?", existing.SchemeID).Update("closes_at", time.Now().AddDate(0, 0, -1))
+				return existing.ApplicantID.String(), existing.SchemeID.String()
+			},
+			inputJSON:     `{"applicantID": "<APPLICANT_ID>", "schemeID": "<SCHEME_ID>"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: "Scheme is not open for applications",
+		},
+		{
+			name: "Scheme full",
+			setupFunc: func() (string, string) {
+				db := setupTestDB(t)
+				existing := createTestApplication(t, db, models.ApplicationWithdrawn)
+				db.Model(&models.Scheme{}).Where("id = ?", existing.SchemeID).Update("max_beneficiaries", 1)
+				db.Create(&models.Application{ApplicantID: uuid.New(), SchemeID: existing.SchemeID, SchemeVersion: 1, Status: models.ApplicationApproved})
+				return existing.ApplicantID.String(), existing.SchemeID.String()
+			},
+			inputJSON:     `{"applicantID": "<APPLICANT_ID>", "schemeID": "<SCHEME_ID>"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: "Scheme has no remaining capacity",
+		},
+		{
+			name: "Created as approved",
+			setupFunc: func() (string, string) {
+				setupTestDB(t)
+				return uuid.NewString(), uuid.NewString()
+			},
+			inputJSON:     `{"applicantID": "<APPLICANT_ID>", "schemeID": "<SCHEME_ID>", "status": "approved"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid initial status",
+		},
 	}
 
 	for _, tt := range tests {
@@ -142,6 +211,41 @@ func TestCreateApplication(t *testing.T) {
 	}
 }
 
+func TestCreateApplicationConcurrent(t *testing.T) {
+	router := setupRouter()
+
+	db := setupTestDB(t)
+	existing := createTestApplication(t, db, models.ApplicationWithdrawn)
+	body := fmt.Sprintf(`{"applicantID": "%s", "schemeID": "%s"}`, existing.ApplicantID, existing.SchemeID)
+
+	const requests = 5
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", "/api/applications", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusConflict: requests - 1}, counts)
+
+	var active int64
+	db.Model(&models.Application{}).Where("applicant_id = ? AND status IN ?", existing.ApplicantID, models.ActiveApplicationStatuses).Count(&active)
+	assert.Equal(t, int64(1), active)
+}
+
 func TestGetAllApplications(t *testing.T) {
 	router := setupRouter()
 
@@ -164,8 +268,8 @@ func TestGetAllApplications(t *testing.T) {
 			setupFunc: func() {
 				db := setupTestDB(t)
 				applications := []models.Application{
-					{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: "pending"},
-					{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: "approved"},
+					{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: models.ApplicationSubmitted},
+					{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: models.ApplicationApproved},
 				}
 				db.Create(&applications)
 			},
@@ -184,10 +288,148 @@ func TestGetAllApplications(t *testing.T) {
 
 			assert.Equal(t, tt.expectedCode, w.Code)
 
-			var response []models.Application
+			var response struct {
+				Items []models.Application `json:"items"`
+				Total int64                `json:"total"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCount, len(response.Items))
+			assert.Equal(t, int64(tt.expectedCount), response.Total)
+		})
+	}
+}
+
+// createTestApplication creates an application with the given status for a two-child
+// applicant under a scheme paying a one-off amount plus a per-child amount.
+func createTestApplication(t *testing.T, db *gorm.DB, status string) models.Application {
+	applicant := models.Applicant{
+		Name: "Jane Doe", EmploymentStatus: "unemployed", Sex: "female",
+		DateOfBirth: time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC),
+		Household: []models.HouseholdMember{
+			{Name: "Jimmy Doe", Relation: "son", DateOfBirth: time.Now().AddDate(-5, 0, 0), EmploymentStatus: "preschool"},
+			{Name: "Jenny Doe", Relation: "daughter", DateOfBirth: time.Now().AddDate(-9, 0, 0), EmploymentStatus: "primary_school"},
+		},
+	}
+	if err := db.Create(&applicant).Error; err != nil {
+		t.Fatalf("Failed to create applicant: %v", err)
+	}
+
+	scheme := models.Scheme{
+		Name: "Family Assistance",
+		Criteria: models.Criteria{Rules: []models.Rule{
+			{Field: "employment_status", Operator: "==", Value: "unemployed"},
+		}},
+		Benefits: models.Benefits{Amount: models.NewMoney(500), PerChild: &models.PerChild{Amount: models.NewMoney(100)}},
+		Version:  1,
+	}
+	if err := db.Create(&scheme).Error; err != nil {
+		t.Fatalf("Failed to create scheme: %v", err)
+	}
+	db.Create(&models.SchemeVersion{SchemeID: scheme.ID, Version: 1, Criteria: scheme.Criteria, Benefits: scheme.Benefits})
+
+	application := models.Application{ApplicantID: applicant.ID, SchemeID: scheme.ID, SchemeVersion: 1, Status: status}
+	if err := db.Create(&application).Error; err != nil {
+		t.Fatalf("Failed to create application: %v", err)
+	}
+	return application
+}
+
+func TestApproveApplicationRecordsEntitlement(t *testing.T) {
+	router := setupRouter()
+
+	db := setupTestDB(t)
+	application := createTestApplication(t, db, models.ApplicationUnderReview)
+
+	// Later benefit changes must not affect the version the application was evaluated against.
+	db.Model(&models.Scheme{}).Where("id = ?", application.SchemeID).Updates(map[string]interface{}{"version": 2})
+	db.Create(&models.SchemeVersion{SchemeID: application.SchemeID, Version: 2, Benefits: models.Benefits{Amount: models.NewMoney(50)}})
+
+	req, _ := http.NewRequest("PUT", "/api/applications/"+application.ID.String(), strings.NewReader(`{"status": "approved"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated models.Application
+	err := db.First(&updated, "id = ?", application.ID).Error
+	assert.NoError(t, err)
+	if assert.NotNil(t, updated.Entitlement) {
+		assert.Equal(t, 2, updated.Entitlement.Children)
+		assert.Equal(t, models.NewMoney(700), updated.Entitlement.OneOff)
+		assert.Equal(t, models.NewMoney(700), updated.Entitlement.Total)
+	}
+}
+
+func TestGetAllApplicationsQuery(t *testing.T) {
+	router := setupRouter()
+
+	db := setupTestDB(t)
+	schemeID := uuid.New()
+	db.Create(&[]models.Application{
+		{ApplicantID: uuid.New(), SchemeID: schemeID, Status: models.ApplicationSubmitted},
+		{ApplicantID: uuid.New(), SchemeID: schemeID, Status: models.ApplicationSubmitted},
+		{ApplicantID: uuid.New(), SchemeID: schemeID, Status: models.ApplicationApproved},
+		{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: models.ApplicationSubmitted},
+	})
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedCount int
+		expectedTotal int64
+		expectedError string
+	}{
+		{
+			name:          "Filtered",
+			query:         "?status=submitted&scheme_id=" + schemeID.String(),
+			expectedCode:  http.StatusOK,
+			expectedCount: 2,
+			expectedTotal: 2,
+		},
+		{
+			name:          "Paged",
+			query:         "?page=2&page_size=3&sort=status",
+			expectedCode:  http.StatusOK,
+			expectedCount: 1,
+			expectedTotal: 4,
+		},
+		{
+			name:          "Unsupported sort key",
+			query:         "?sort=applicant_id",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `unsupported sort key \"applicant_id\"`,
+		},
+		{
+			name:          "Invalid UUID filter",
+			query:         "?scheme_id=abc",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "scheme_id must be a UUID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/applications"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				return
+			}
+
+			var response struct {
+				Items []models.Application `json:"items"`
+				Total int64                `json:"total"`
+			}
 			err := json.Unmarshal(w.Body.Bytes(), &response)
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expectedCount, len(response))
+			assert.Equal(t, tt.expectedCount, len(response.Items))
+			assert.Equal(t, tt.expectedTotal, response.Total)
 		})
 	}
 }
@@ -206,11 +448,132 @@ func TestUpdateApplication(t *testing.T) {
 			name: "Valid update",
 			setupFunc: func() string {
 				db := setupTestDB(t)
-				application := models.Application{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: "pending"}
-				db.Create(&application)
+				return createTestApplication(t, db, models.ApplicationSubmitted).ID.String()
+			},
+			inputJSON:     `{"status": "under_review"}`,
+			expectedCode:  http.StatusOK,
+			expectedError: "",
+		},
+		{
+			name: "Approve under review",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestApplication(t, db, models.ApplicationUnderReview).ID.String()
+			},
+			inputJSON:     `{"status": "approved"}`,
+			expectedCode:  http.StatusOK,
+			expectedError: "",
+		},
+		{
+			name: "Approve applicant no longer eligible",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				application := createTestApplication(t, db, models.ApplicationUnderReview)
+				db.Model(&models.Applicant{}).Where("id = ?", application.ApplicantID).Update("employment_status", "employed")
 				return application.ID.String()
 			},
 			inputJSON:     `{"status": "approved"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: `"actual":"employed"`,
+		},
+		{
+			name: "Approve under a version with legacy benefits",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				application := createTestApplication(t, db, models.ApplicationUnderReview)
+				db.Exec(`UPDATE scheme_versions SET benefits = '{"amount": "lots"}' WHERE scheme_id = ?`, application.SchemeID)
+				return application.ID.String()
+			},
+			inputJSON:     `{"status": "approved"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: `"path":"benefits"`,
+		},
+		{
+			name: "Appeal after reapplying",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				rejected := createTestApplication(t, db, models.ApplicationRejected)
+				db.Create(&models.Application{ApplicantID: rejected.ApplicantID, SchemeID: rejected.SchemeID, SchemeVersion: 1, Status: models.ApplicationSubmitted})
+				return rejected.ID.String()
+			},
+			inputJSON:     `{"status": "appealed"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: "Applicant already has an active application for this scheme",
+		},
+		{
+			name: "Approval over budget",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				application := createTestApplication(t, db, models.ApplicationUnderReview)
+				// The applicant is entitled to 700 and only 500 of the budget is left.
+				db.Model(&models.Scheme{}).Where("id = ?", application.SchemeID).Update("budget", models.NewMoney(1000))
+				db.Create(&models.Application{
+					ApplicantID: uuid.New(), SchemeID: application.SchemeID, SchemeVersion: 1, Status: models.ApplicationApproved,
+					Entitlement: &models.Entitlement{OneOff: models.NewMoney(500), Vouchers: []models.Voucher{}, Total: models.NewMoney(500)},
+				})
+				return application.ID.String()
+			},
+			inputJSON:     `{"status": "approved"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: `"remaining_budget":{"amount":500,"currency":"SGD"}`,
+		},
+		{
+			name: "Invalid transition",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestApplication(t, db, models.ApplicationSubmitted).ID.String()
+			},
+			inputJSON:     `{"status": "approved"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: `"allowed":["under_review","withdrawn"]`,
+		},
+		{
+			name: "Closed application",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestApplication(t, db, models.ApplicationWithdrawn).ID.String()
+			},
+			inputJSON:     `{"status": "submitted"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: `"allowed":[]`,
+		},
+		{
+			name: "Unknown status",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestApplication(t, db, models.ApplicationSubmitted).ID.String()
+			},
+			inputJSON:     `{"status": "banana"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Unknown status",
+		},
+		{
+			name: "Rejection without reason",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestApplication(t, db, models.ApplicationUnderReview).ID.String()
+			},
+			inputJSON:     `{"status": "rejected", "reason": "  "}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "A reason is required to reject an application",
+		},
+		{
+			name: "Rejection with reason",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestApplication(t, db, models.ApplicationUnderReview).ID.String()
+			},
+			inputJSON:     `{"status": "rejected", "reason": "Household income above threshold"}`,
+			expectedCode:  http.StatusOK,
+			expectedError: "",
+		},
+		{
+			name: "Appeal rejection",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestApplication(t, db, models.ApplicationRejected).ID.String()
+			},
+			inputJSON:     `{"status": "appealed"}`,
 			expectedCode:  http.StatusOK,
 			expectedError: "",
 		},
@@ -228,7 +591,7 @@ func TestUpdateApplication(t *testing.T) {
 			name: "Invalid input",
 			setupFunc: func() string {
 				db := setupTestDB(t)
-				application := models.Application{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: "pending"}
+				application := models.Application{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: models.ApplicationSubmitted}
 				db.Create(&application)
 				return application.ID.String()
 			},
@@ -271,7 +634,7 @@ func TestDeleteApplication(t *testing.T) {
 			name: "Valid delete",
 			setupFunc: func() string {
 				db := setupTestDB(t)
-				application := models.Application{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: "pending"}
+				application := models.Application{ApplicantID: uuid.New(), SchemeID: uuid.New(), Status: models.ApplicationSubmitted}
 				db.Create(&application)
 				return application.ID.String()
 			},
@@ -287,6 +650,17 @@ func TestDeleteApplication(t *testing.T) {
 			expectedCode:  http.StatusNotFound,
 			expectedError: "application not found",
 		},
+		{
+			name: "Application with history",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				application := createTestApplication(t, db, models.ApplicationApproved)
+				db.Create(&models.ApplicationStatusChange{ApplicationID: application.ID, ToStatus: models.ApplicationApproved, ChangedBy: testAdminID})
+				return application.ID.String()
+			},
+			expectedCode:  http.StatusConflict,
+			expectedError: "withdraw it instead",
+		},
 	}
 
 	for _, tt := range tests {
@@ -306,3 +680,56 @@ func TestDeleteApplication(t *testing.T) {
 		})
 	}
 }
+
+func TestGetApplicationHistory(t *testing.T) {
+	router := setupRouter()
+
+	db := setupTestDB(t)
+	application := createTestApplication(t, db, models.ApplicationUnderReview)
+
+	for _, body := range []string{
+		`{"status": "rejected", "reason": "Incomplete documents"}`,
+		`{"status": "appealed", "reason": "Documents provided"}`,
+	} {
+		req, _ := http.NewRequest("PUT", "/api/applications/"+application.ID.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// A rejected transition must not be recorded.
+	req, _ := http.NewRequest("PUT", "/api/applications/"+application.ID.String(), strings.NewReader(`{"status": "disbursed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/applications/"+application.ID.String()+"/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Items []models.ApplicationStatusChange `json:"items"`
+		Total int64                            `json:"total"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.Total)
+	history := response.Items
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, models.ApplicationUnderReview, history[0].FromStatus)
+		assert.Equal(t, models.ApplicationRejected, history[0].ToStatus)
+		assert.Equal(t, "Incomplete documents", history[0].Remark)
+		assert.Equal(t, testAdminID, history[0].ChangedBy)
+		assert.Equal(t, models.ApplicationRejected, history[1].FromStatus)
+		assert.Equal(t, models.ApplicationAppealed, history[1].ToStatus)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/applications/"+uuid.NewString()+"/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/google/uuid"
+)
+
+// initialStatuses are the statuses an application may be created with.
+var initialStatuses = []string{models.ApplicationDraft, models.ApplicationSubmitted}
+
+// transitions lists the statuses an application may move to from each status.
+// Disbursed and withdrawn applications are closed; an appeal sends a rejected
+// application back for review.
+var transitions = map[string][]string{
+	models.ApplicationDraft:       {models.ApplicationSubmitted, models.ApplicationWithdrawn},
+	models.ApplicationSubmitted:   {models.ApplicationUnderReview, models.ApplicationWithdrawn},
+	models.ApplicationUnderReview: {models.ApplicationApproved, models.ApplicationRejected, models.ApplicationWithdrawn},
+	models.ApplicationApproved:    {models.ApplicationDisbursed, models.ApplicationWithdrawn},
+	models.ApplicationRejected:    {models.ApplicationAppealed},
+	models.ApplicationAppealed:    {models.ApplicationUnderReview},
+	models.ApplicationDisbursed:   {},
+	models.ApplicationWithdrawn:   {},
+}
+
+// transitionError reports a status change the workflow does not allow.
+type transitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("cannot change application status from %q to %q", e.From, e.To)
+}
+
+// ineligibleError blocks an approval because the applicant no longer meets
+// the scheme's criteria.
+type ineligibleError struct {
+	Eligibility utils.SchemeEligibility
+}
+
+func (e *ineligibleError) Error() string {
+	return "Applicant is no longer eligible for this scheme"
+}
+
+// duplicateError blocks an application because the applicant already has an
+// active application for the scheme.
+type duplicateError struct {
+	ExistingID uuid.UUID
+}
+
+func (e *duplicateError) Error() string {
+	return "Applicant already has an active application for this scheme"
+}
+
+var errSchemeClosed = errors.New("Scheme is not open for applications")
+
+var errHasStatusHistory = errors.New("application has status history and cannot be deleted; withdraw it instead")
+
+// capacityError blocks an application or approval because the scheme has run
+// out of beneficiary places or budget.
+type capacityError struct {
+	Message  string
+	Capacity utils.SchemeCapacity
+}
+
+func (e *capacityError) Error() string {
+	return e.Message
+}
+
+func isKnownStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// checkTransition returns a transitionError unless the workflow allows moving
+// from one status to the other.
+func checkTransition(from, to string) error {
+	allowed, ok := transitions[from]
+	if !ok {
+		// A status outside the workflow has no way forward.
+		allowed = []string{}
+	}
+	for _, status := range allowed {
+		if status == to {
+			return nil
+		}
+	}
+	return &transitionError{From: from, To: to, Allowed: allowed}
+}
@@ -1,22 +1,36 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 var JWTSecret = []byte("")
 
-func GenerateJWT(email string) (string, error) {
+// Claims are the JWT claims of an authenticated administrator. The subject is
+// the administrator's ID; their role is loaded on every request so that role
+// changes and deletions take effect immediately.
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+func GenerateJWT(subject string) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &jwt.RegisteredClaims{
-		Subject:   email,
-		ExpiresAt: jwt.NewNumericDate(expirationTime),
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -27,8 +41,8 @@ func GenerateJWT(email string) (string, error) {
 	return tokenString, nil
 }
 
-func ValidateJWT(tokenString string) (*jwt.RegisteredClaims, error) {
-	claims := &jwt.RegisteredClaims{}
+func ValidateJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return JWTSecret, nil
@@ -71,7 +85,24 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("claims", claims)
+		adminID, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+		var admin models.Administrator
+		if err := db.DB.Select("id", "role").First(&admin, "id = ?", adminID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Administrator no longer exists"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("administrator", admin)
 
 		c.Next()
 	}
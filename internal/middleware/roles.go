@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Permission is something an administrator may be allowed to do.
+type Permission string
+
+const (
+	Read                 Permission = "read"                  // Read applicants, applications, schemes and administrators
+	ManageAdministrators Permission = "manage_administrators" // Create, update and delete administrators
+	ManageSchemes        Permission = "manage_schemes"        // Create, update and delete schemes
+	ManageApplicants     Permission = "manage_applicants"     // Create, update, merge and delete applicants
+	ManageApplications   Permission = "manage_applications"   // Create, decide and delete applications
+	ViewNationalIDs      Permission = "view_national_ids"     // See national IDs unmasked
+)
+
+// rolePermissions lists what each role may do.
+var rolePermissions = map[string][]Permission{
+	models.RoleSuperAdmin:    {Read, ManageAdministrators, ManageSchemes, ManageApplicants, ManageApplications, ViewNationalIDs},
+	models.RoleSchemeManager: {Read, ManageSchemes},
+	models.RoleCaseworker:    {Read, ManageApplicants, ManageApplications, ViewNationalIDs},
+	models.RoleAuditor:       {Read},
+}
+
+// HasPermission reports whether the role grants the permission. Unknown roles
+// grant nothing.
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission rejects requests from administrators whose current role
+// does not grant the permission. It must run after AuthMiddleware.
+func RequirePermission(permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := administratorFrom(c); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if !HasPermission(Role(c), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role does not permit this action", "permission": permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
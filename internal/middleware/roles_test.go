@@ -0,0 +1,176 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/bensiauu/financial-assistance-scheme/internal/middleware"
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=5432 sslmode=disable TimeZone=UTC", os.Getenv("DB_HOST"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
+	testDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	testDB.AutoMigrate(&models.Administrator{})
+
+	db.DB = testDB
+
+	t.Cleanup(func() {
+		sqlDB, err := db.DB.DB()
+		if err != nil {
+			t.Logf("Failed to get database connection: %v", err)
+		}
+
+		_, err = sqlDB.Exec("DROP TABLE IF EXISTS administrators")
+		if err != nil {
+			t.Logf("failed to drop db: %v", err)
+		}
+		sqlDB.Close()
+	})
+
+	return testDB
+}
+
+// createTestAdministrator stores an administrator with the role and returns a
+// token for them.
+func createTestAdministrator(t *testing.T, db *gorm.DB, role string) (models.Administrator, string) {
+	admin := models.Administrator{
+		Name:         "Test Admin",
+		Email:        uuid.NewString() + "@example.com",
+		PasswordHash: "hash",
+		Role:         role,
+	}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatalf("Failed to create administrator: %v", err)
+	}
+	token, err := middleware.GenerateJWT(admin.ID.String())
+	assert.NoError(t, err)
+	return admin, token
+}
+
+func setupRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware())
+	router.GET("/schemes", middleware.RequirePermission(middleware.Read), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"national_ids": middleware.CanViewNationalIDs(c)})
+	})
+	router.POST("/schemes", middleware.RequirePermission(middleware.ManageSchemes), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"subject": middleware.Subject(c)})
+	})
+	return router
+}
+
+func TestRequirePermission(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name         string
+		role         string
+		method       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Auditor may read",
+			role:         models.RoleAuditor,
+			method:       "GET",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"national_ids":false}`,
+		},
+		{
+			name:         "Caseworker sees national IDs",
+			role:         models.RoleCaseworker,
+			method:       "GET",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"national_ids":true}`,
+		},
+		{
+			name:         "Auditor may not write",
+			role:         models.RoleAuditor,
+			method:       "POST",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `"permission":"manage_schemes"`,
+		},
+		{
+			name:         "Caseworker may not manage schemes",
+			role:         models.RoleCaseworker,
+			method:       "POST",
+			expectedCode: http.StatusForbidden,
+			expectedBody: "Your role does not permit this action",
+		},
+		{
+			name:         "Scheme manager may manage schemes",
+			role:         models.RoleSchemeManager,
+			method:       "POST",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"subject":"`,
+		},
+		{
+			name:         "Unknown role",
+			role:         "owner",
+			method:       "GET",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `"permission":"read"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			_, token := createTestAdministrator(t, db, tt.role)
+
+			req, _ := http.NewRequest(tt.method, "/schemes", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestRoleChangesTakeEffectImmediately(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+	admin, token := createTestAdministrator(t, db, models.RoleSchemeManager)
+
+	db.Model(&admin).Update("role", models.RoleAuditor)
+
+	req, _ := http.NewRequest("POST", "/schemes", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	db.Delete(&admin)
+
+	req, _ = http.NewRequest("GET", "/schemes", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Administrator no longer exists")
+}
+
+func TestHasPermission(t *testing.T) {
+	assert.True(t, middleware.HasPermission(models.RoleSuperAdmin, middleware.ManageAdministrators))
+	assert.False(t, middleware.HasPermission(models.RoleSchemeManager, middleware.ManageAdministrators))
+	assert.False(t, middleware.HasPermission(models.RoleSchemeManager, middleware.ManageApplications))
+	assert.False(t, middleware.HasPermission(models.RoleAuditor, middleware.ViewNationalIDs))
+	assert.False(t, middleware.HasPermission("owner", middleware.Read))
+}
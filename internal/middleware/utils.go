@@ -3,6 +3,9 @@ package middleware
 import (
 	"crypto/rand"
 	"encoding/hex"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/gin-gonic/gin"
 )
 
 func GenerateSecureKey(size int) (string, error) {
@@ -14,3 +17,38 @@ func GenerateSecureKey(size int) (string, error) {
 
 	return hex.EncodeToString(key), nil
 }
+
+// Subject returns the ID of the administrator loaded by AuthMiddleware, or an
+// empty string if the request was not authenticated.
+func Subject(c *gin.Context) string {
+	admin, ok := administratorFrom(c)
+	if !ok {
+		return ""
+	}
+	return admin.ID.String()
+}
+
+// Role returns the caller's current role as loaded by AuthMiddleware, or an
+// empty string if the request was not authenticated.
+func Role(c *gin.Context) string {
+	admin, ok := administratorFrom(c)
+	if !ok {
+		return ""
+	}
+	return admin.Role
+}
+
+// CanViewNationalIDs reports whether the caller may see national IDs
+// unmasked.
+func CanViewNationalIDs(c *gin.Context) bool {
+	return HasPermission(Role(c), ViewNationalIDs)
+}
+
+func administratorFrom(c *gin.Context) (models.Administrator, bool) {
+	value, ok := c.Get("administrator")
+	if !ok {
+		return models.Administrator{}, false
+	}
+	admin, ok := value.(models.Administrator)
+	return admin, ok
+}
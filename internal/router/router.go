@@ -15,32 +15,49 @@ func SetupRouter() *gin.Engine {
 
 	router.POST("/login", auth.Login)
 
+	// Every route below needs a token, and a role that grants the route's
+	// permission.
 	router.Use(middleware.AuthMiddleware())
 	router.Group("/api").Group("/admin").
-		POST("/", admin.CreateAdministrator).
-		GET("/", admin.GetAllAdministrators).
-		GET("/:id", admin.GetAdministratorByID).
-		PUT("/:id", admin.UpdateAdministrator).
-		DELETE("/:id", admin.DeleteAdministrator)
+		POST("/", middleware.RequirePermission(middleware.ManageAdministrators), admin.CreateAdministrator).
+		GET("/", middleware.RequirePermission(middleware.Read), admin.GetAllAdministrators).
+		GET("/:id", middleware.RequirePermission(middleware.Read), admin.GetAdministratorByID).
+		PUT("/:id", middleware.RequirePermission(middleware.ManageAdministrators), admin.UpdateAdministrator).
+		DELETE("/:id", middleware.RequirePermission(middleware.ManageAdministrators), admin.DeleteAdministrator)
 
 	router.Group("/api").Group("/applicants").
-		POST("/", applicants.CreateApplicant).
-		GET("/", applicants.GetAllApplicants).
-		GET("/:id", applicants.GetApplicantByID).
-		PUT("/:id", applicants.UpdateApplicant).
-		DELETE("/:id", applicants.DeleteApplicant)
+		POST("/", middleware.RequirePermission(middleware.ManageApplicants), applicants.CreateApplicant).
+		GET("/", middleware.RequirePermission(middleware.Read), applicants.GetAllApplicants).
+		GET("/search", middleware.RequirePermission(middleware.Read), applicants.SearchApplicants).
+		GET("/duplicates", middleware.RequirePermission(middleware.Read), applicants.GetDuplicateApplicants).
+		POST("/duplicates/detect", middleware.RequirePermission(middleware.ManageApplicants), applicants.DetectDuplicateApplicants).
+		GET("/:id", middleware.RequirePermission(middleware.Read), applicants.GetApplicantByID).
+		PUT("/:id", middleware.RequirePermission(middleware.ManageApplicants), applicants.UpdateApplicant).
+		DELETE("/:id", middleware.RequirePermission(middleware.ManageApplicants), applicants.DeleteApplicant).
+		POST("/:id/merge", middleware.RequirePermission(middleware.ManageApplicants), applicants.MergeApplicants).
+		GET("/:id/household", middleware.RequirePermission(middleware.Read), applicants.GetHousehold).
+		POST("/:id/household", middleware.RequirePermission(middleware.ManageApplicants), applicants.AddHouseholdMember).
+		PUT("/:id/household/:memberId", middleware.RequirePermission(middleware.ManageApplicants), applicants.UpdateHouseholdMember).
+		DELETE("/:id/household/:memberId", middleware.RequirePermission(middleware.ManageApplicants), applicants.RemoveHouseholdMember)
 
 	router.Group("/api").Group("/applications").
-		POST("/", applications.CreateApplication).
-		GET("/", applications.GetAllApplication).
-		GET("/:id", applications.GetApplicationByID).
-		PUT("/:id", applications.UpdateApplication).
-		DELETE("/:id", applications.DeleteApplication)
+		POST("/", middleware.RequirePermission(middleware.ManageApplications), applications.CreateApplication).
+		GET("/", middleware.RequirePermission(middleware.Read), applications.GetAllApplication).
+		GET("/:id", middleware.RequirePermission(middleware.Read), applications.GetApplicationByID).
+		PUT("/:id", middleware.RequirePermission(middleware.ManageApplications), applications.UpdateApplication).
+		DELETE("/:id", middleware.RequirePermission(middleware.ManageApplications), applications.DeleteApplication).
+		GET("/:id/history", middleware.RequirePermission(middleware.Read), applications.GetApplicationHistory)
 
 	router.Group("/api").Group("/schemes").
-		POST("/", schemes.CreateScheme).
-		GET("/", schemes.GetAllSchemes).
-		GET("/eligible/", schemes.GetEligibleSchemes)
+		POST("/", middleware.RequirePermission(middleware.ManageSchemes), schemes.CreateScheme).
+		POST("/validate", middleware.RequirePermission(middleware.ManageSchemes), schemes.ValidateScheme).
+		GET("/", middleware.RequirePermission(middleware.Read), schemes.GetAllSchemes).
+		GET("/eligible/", middleware.RequirePermission(middleware.Read), schemes.GetEligibleSchemes).
+		GET("/eligible/explain", middleware.RequirePermission(middleware.Read), schemes.ExplainEligibility).
+		GET("/:id", middleware.RequirePermission(middleware.Read), schemes.GetSchemeByID).
+		PUT("/:id", middleware.RequirePermission(middleware.ManageSchemes), schemes.UpdateScheme).
+		DELETE("/:id", middleware.RequirePermission(middleware.ManageSchemes), schemes.DeleteScheme).
+		GET("/:id/versions", middleware.RequirePermission(middleware.Read), schemes.GetSchemeVersions)
 
 	return router
 }
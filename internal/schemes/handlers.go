@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
 	"github.com/bensiauu/financial-assistance-scheme/models"
 	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateScheme(c *gin.Context) {
@@ -16,30 +23,356 @@ func CreateScheme(c *gin.Context) {
 		return
 	}
 
-	if err := db.DB.Create(&scheme).Error; err != nil {
+	errs := validateScheme(&scheme.Criteria, &scheme.Benefits)
+	errs = append(errs, validateLimits(scheme)...)
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scheme", "details": errs})
+		return
+	}
+
+	scheme.Version = 1
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&scheme).Error; err != nil {
+			return err
+		}
+		return recordSchemeVersion(tx, scheme)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "scheme created successfully"})
 }
+
+// ValidateScheme runs the same validation as CreateScheme without storing
+// anything.
+func ValidateScheme(c *gin.Context) {
+	var scheme models.Scheme
+	if err := c.ShouldBindBodyWithJSON(&scheme); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	errs := validateScheme(&scheme.Criteria, &scheme.Benefits)
+	errs = append(errs, validateLimits(scheme)...)
+	c.JSON(http.StatusOK, gin.H{"valid": len(errs) == 0, "errors": errs})
+}
+
+// validateScheme validates whichever of the criteria and benefits are given.
+// Benefits errors are addressed from "benefits" to tell them apart from
+// criteria errors.
+func validateScheme(criteria *models.Criteria, benefits *models.Benefits) []utils.CriteriaError {
+	errs := make([]utils.CriteriaError, 0)
+	if criteria != nil {
+		errs = append(errs, utils.ValidateCriteria(*criteria)...)
+	}
+	if benefits != nil {
+		for _, err := range utils.ValidateBenefits(*benefits) {
+			err.Path = "benefits." + err.Path
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validateLimits checks the scheme's application window, beneficiary limit
+// and budget.
+func validateLimits(scheme models.Scheme) []utils.CriteriaError {
+	errs := make([]utils.CriteriaError, 0)
+	if scheme.OpensAt != nil && scheme.ClosesAt != nil && !scheme.ClosesAt.After(*scheme.OpensAt) {
+		errs = append(errs, utils.CriteriaError{Path: "closes_at", Message: "must be after opens_at"})
+	}
+	if scheme.MaxBeneficiaries < 0 {
+		errs = append(errs, utils.CriteriaError{Path: "max_beneficiaries", Message: "must not be negative"})
+	}
+	if scheme.Budget.CurrencyCode() != models.DefaultCurrency {
+		errs = append(errs, utils.CriteriaError{
+			Path:    "budget",
+			Message: fmt.Sprintf("unsupported currency %q, expected %s", scheme.Budget.CurrencyCode(), models.DefaultCurrency),
+		})
+	}
+	if scheme.Budget.Amount < 0 {
+		errs = append(errs, utils.CriteriaError{Path: "budget", Message: "must not be negative"})
+	}
+	return errs
+}
+
+// recordSchemeVersion stores a snapshot of the scheme's current criteria and benefits.
+func recordSchemeVersion(tx *gorm.DB, scheme models.Scheme) error {
+	version := models.SchemeVersion{
+		SchemeID: scheme.ID,
+		Version:  scheme.Version,
+		Criteria: scheme.Criteria,
+		Benefits: scheme.Benefits,
+	}
+	return tx.Create(&version).Error
+}
+
+func GetSchemeByID(c *gin.Context) {
+	id := c.Param("id")
+	var scheme models.Scheme
+
+	if err := db.DB.First(&scheme, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scheme not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scheme)
+}
+
+type updateSchemeInput struct {
+	Name             *string          `json:"name,omitempty"`
+	Criteria         *models.Criteria `json:"criteria,omitempty"`
+	Benefits         *models.Benefits `json:"benefits,omitempty"`
+	OpensAt          optionalTime     `json:"opens_at"`
+	ClosesAt         optionalTime     `json:"closes_at"`
+	MaxBeneficiaries *int             `json:"max_beneficiaries,omitempty"`
+	Budget           *models.Money    `json:"budget,omitempty"`
+}
+
+// optionalTime tells a time that was left out of the request apart from one
+// that was explicitly cleared with null.
+type optionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (t *optionalTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	return json.Unmarshal(data, &t.Value)
+}
+
+// invalidSchemeError reports validation errors found after an update has been
+// applied to the stored scheme.
+type invalidSchemeError []utils.CriteriaError
+
+func (e invalidSchemeError) Error() string {
+	return "invalid scheme"
+}
+
+func UpdateScheme(c *gin.Context) {
+	id := c.Param("id")
+
+	var input updateSchemeInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errs := validateScheme(input.Criteria, input.Benefits); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scheme", "details": errs})
+		return
+	}
+
+	var scheme models.Scheme
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the scheme so concurrent updates cannot record the same version twice.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&scheme, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if input.Name != nil {
+			scheme.Name = *input.Name
+		}
+		if input.OpensAt.Set {
+			scheme.OpensAt = input.OpensAt.Value
+		}
+		if input.ClosesAt.Set {
+			scheme.ClosesAt = input.ClosesAt.Value
+		}
+		if input.MaxBeneficiaries != nil {
+			scheme.MaxBeneficiaries = *input.MaxBeneficiaries
+		}
+		if input.Budget != nil {
+			scheme.Budget = *input.Budget
+		}
+		if errs := validateLimits(scheme); len(errs) > 0 {
+			return invalidSchemeError(errs)
+		}
+
+		versioned := false
+		if input.Criteria != nil {
+			scheme.Criteria = *input.Criteria
+			versioned = true
+		}
+		if input.Benefits != nil {
+			scheme.Benefits = *input.Benefits
+			versioned = true
+		}
+		if versioned {
+			scheme.Version++
+		}
+
+		if err := tx.Save(&scheme).Error; err != nil {
+			return err
+		}
+		if versioned {
+			return recordSchemeVersion(tx, scheme)
+		}
+		return nil
+	})
+	if err != nil {
+		var invalidErr invalidSchemeError
+		if errors.As(err, &invalidErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scheme", "details": invalidErr})
+			return
+		}
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scheme not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "scheme updated successfully", "version": scheme.Version})
+}
+
+// errSchemeHasApplications blocks deleting a scheme that has been applied
+// for, since applications reference its version history.
+var errSchemeHasApplications = errors.New("scheme has existing applications")
+
+func DeleteScheme(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "scheme not found"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the scheme blocks new applications, which share-lock it,
+		// until the scheme is gone.
+		var scheme models.Scheme
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&scheme, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		var applicationCount int64
+		if err := tx.Model(&models.Application{}).Where("scheme_id = ?", scheme.ID).Count(&applicationCount).Error; err != nil {
+			return err
+		}
+		if applicationCount > 0 {
+			return errSchemeHasApplications
+		}
+
+		if err := tx.Where("scheme_id = ?", scheme.ID).Delete(&models.SchemeVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&scheme).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "scheme not found"})
+		return
+	case errors.Is(err, errSchemeHasApplications) || utils.IsStatusHistoryViolation(err):
+		c.JSON(http.StatusConflict, gin.H{"error": errSchemeHasApplications.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete scheme"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "scheme deleted successfully"})
+}
+
+var schemeVersionListOptions = utils.ListOptions{
+	Sorts:       []string{"version", "created_at"},
+	DefaultSort: "version",
+}
+
+func GetSchemeVersions(c *gin.Context) {
+	id := c.Param("id")
+
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), schemeVersionListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var scheme models.Scheme
+	if err := db.DB.First(&scheme, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scheme not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	versions := []models.SchemeVersion{}
+	page, err := query.Find(db.DB.Where("scheme_id = ?", scheme.ID), &versions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	page.Items = versions
+	c.JSON(http.StatusOK, page)
+}
+
+// schemeWithCapacity is a scheme as listed, with how much of it is left.
+type schemeWithCapacity struct {
+	models.Scheme
+	Capacity utils.SchemeCapacity `json:"capacity"`
+}
+
+var schemeListOptions = utils.ListOptions{
+	Filters:     map[string]utils.FilterKind{"name": utils.StringFilter},
+	Sorts:       []string{"name", "created_at", "updated_at"},
+	DefaultSort: "name",
+}
+
 func GetAllSchemes(c *gin.Context) {
+	query, err := utils.ParseListQuery(c.Request.URL.Query(), schemeListOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var schemes []models.Scheme
-	if err := db.DB.Find(&schemes).Error; err != nil {
+	page, err := query.Find(db.DB, &schemes)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(schemes) == 0 {
-		c.JSON(http.StatusOK, []models.Scheme{})
+	capacities, err := utils.GetSchemeCapacities(db.DB, schemes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, schemes)
+	response := make([]schemeWithCapacity, 0, len(schemes))
+	for _, scheme := range schemes {
+		response = append(response, schemeWithCapacity{Scheme: scheme, Capacity: capacities[scheme.ID]})
+	}
+
+	page.Items = response
+	c.JSON(http.StatusOK, page)
+}
+
+// parseAsOf reads the optional as_of query parameter, defaulting to today.
+func parseAsOf(c *gin.Context) (time.Time, error) {
+	asOf := c.Query("as_of")
+	if asOf == "" {
+		return utils.Clock(), nil
+	}
+	return time.Parse("2006-01-02", asOf)
 }
 
 func GetEligibleSchemes(c *gin.Context) {
 	applicantID := c.Query("applicant")
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid as_of date, expected YYYY-MM-DD"})
+		return
+	}
+
 	var applicant models.Applicant
 	if err := db.DB.First(&applicant, "id = ?", applicantID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Applicant not found"})
@@ -52,11 +385,50 @@ func GetEligibleSchemes(c *gin.Context) {
 		return
 	}
 
-	eligibleSchemes, err := utils.GetEligibleSchemes(applicantID)
+	eligibleSchemes, skippedSchemes, err := utils.GetEligibleSchemes(applicantID, asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if eligibleSchemes == nil {
+		eligibleSchemes = []utils.EligibleScheme{}
+	}
+	if skippedSchemes == nil {
+		skippedSchemes = []utils.SkippedScheme{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"as_of":   asOf.Format("2006-01-02"),
+		"schemes": eligibleSchemes,
+		"skipped": skippedSchemes,
+	})
+}
+
+func ExplainEligibility(c *gin.Context) {
+	applicantID := c.Query("applicant")
+	schemeID := c.Query("scheme")
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid as_of date, expected YYYY-MM-DD"})
+		return
+	}
+
+	var applicant models.Applicant
+	if err := db.DB.First(&applicant, "id = ?", applicantID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Applicant not found"})
+		return
+	}
+
+	results, err := utils.ExplainEligibility(applicantID, schemeID, asOf)
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scheme not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, eligibleSchemes)
+	c.JSON(http.StatusOK, results)
 }
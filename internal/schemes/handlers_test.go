@@ -11,6 +11,7 @@ import (
 	"time"
 
 	handlers "github.com/bensiauu/financial-assistance-scheme/internal/schemes"
+	"github.com/bensiauu/financial-assistance-scheme/internal/utils"
 	"github.com/bensiauu/financial-assistance-scheme/models"
 	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
 	"github.com/gin-gonic/gin"
@@ -27,7 +28,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	testDB.AutoMigrate(&models.Applicant{}, &models.Scheme{})
+	testDB.AutoMigrate(&models.Applicant{}, &models.HouseholdMember{}, &models.Scheme{}, &models.SchemeVersion{}, &models.Application{})
 
 	db.DB = testDB
 
@@ -38,7 +39,10 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		}
 
 		sqlDB.Exec("DROP table IF EXISTS applicants CASCADE")
+		sqlDB.Exec("DROP table IF EXISTS household_members CASCADE")
 		sqlDB.Exec("DROP table IF EXISTS schemes CASCADE")
+		sqlDB.Exec("DROP table IF EXISTS scheme_versions CASCADE")
+		sqlDB.Exec("DROP table IF EXISTS applications CASCADE")
 		sqlDB.Close()
 	})
 
@@ -48,8 +52,14 @@ func setupTestDB(t *testing.T) *gorm.DB {
 func setupRouter() *gin.Engine {
 	router := gin.Default()
 	router.POST("/api/schemes", handlers.CreateScheme)
+	router.POST("/api/schemes/validate", handlers.ValidateScheme)
 	router.GET("/api/schemes", handlers.GetAllSchemes)
 	router.GET("/api/schemes/eligible", handlers.GetEligibleSchemes)
+	router.GET("/api/schemes/eligible/explain", handlers.ExplainEligibility)
+	router.GET("/api/schemes/:id", handlers.GetSchemeByID)
+	router.PUT("/api/schemes/:id", handlers.UpdateScheme)
+	router.DELETE("/api/schemes/:id", handlers.DeleteScheme)
+	router.GET("/api/schemes/:id/versions", handlers.GetSchemeVersions)
 	return router
 }
 
@@ -89,6 +99,32 @@ func TestCreateScheme(t *testing.T) {
 			expectedCode:  http.StatusBadRequest,
 			expectedError: "json: cannot unmarshal",
 		},
+		{
+			name: "Invalid criteria",
+			inputJSON: `{
+                "name": "Low Income Assistance",
+                "criteria": {
+                    "rules": [
+                        {"field": "income", "operator": "<=", "value": "20000"}
+                    ]
+                },
+                "benefits": {"amount": 1000}
+            }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "rules[0].value",
+		},
+		{
+			name: "Invalid limits",
+			inputJSON: `{
+                "name": "Low Income Assistance",
+                "criteria": {"rules": []},
+                "benefits": {"amount": 1000},
+                "max_beneficiaries": -1,
+                "budget": -500
+            }`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `"path":"budget"`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -113,6 +149,79 @@ func TestCreateScheme(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateSchemeWithLimits(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	body := `{
+        "name": "Seasonal Assistance",
+        "criteria": {"rules": []},
+        "benefits": {"amount": 1000},
+        "opens_at": "2024-01-01T00:00:00Z",
+        "closes_at": "2024-12-31T00:00:00Z",
+        "max_beneficiaries": 50
+    }`
+	req, _ := http.NewRequest("POST", "/api/schemes", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var scheme models.Scheme
+	db.First(&scheme, "name = ?", "Seasonal Assistance")
+	if assert.NotNil(t, scheme.OpensAt) && assert.NotNil(t, scheme.ClosesAt) {
+		assert.True(t, scheme.OpensAt.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+		assert.True(t, scheme.ClosesAt.Equal(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)))
+	}
+	assert.Equal(t, 50, scheme.MaxBeneficiaries)
+}
+
+func TestGetAllSchemesCapacity(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	limited := createTestScheme(t, db)
+	db.Model(&limited).Updates(map[string]interface{}{"max_beneficiaries": 3, "budget": models.NewMoney(1000)})
+	unlimited := createTestScheme(t, db)
+
+	for _, status := range []string{models.ApplicationApproved, models.ApplicationDisbursed, models.ApplicationUnderReview} {
+		db.Create(&models.Application{
+			ApplicantID: uuid.New(), SchemeID: limited.ID, SchemeVersion: 1, Status: status,
+			Entitlement: &models.Entitlement{OneOff: models.NewMoney(300), Vouchers: []models.Voucher{}, Total: models.NewMoney(300)},
+		})
+	}
+
+	req, _ := http.NewRequest("GET", "/api/schemes", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Items []struct {
+			ID       uuid.UUID            `json:"ID"`
+			Capacity utils.SchemeCapacity `json:"capacity"`
+		} `json:"items"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	capacities := map[uuid.UUID]utils.SchemeCapacity{}
+	for _, scheme := range response.Items {
+		capacities[scheme.ID] = scheme.Capacity
+	}
+
+	remainingBeneficiaries, remainingBudget := 1, models.NewMoney(400)
+	assert.Equal(t, utils.SchemeCapacity{
+		Beneficiaries:          2,
+		RemainingBeneficiaries: &remainingBeneficiaries,
+		BudgetUsed:             models.NewMoney(600),
+		RemainingBudget:        &remainingBudget,
+	}, capacities[limited.ID])
+	assert.Equal(t, utils.SchemeCapacity{}, capacities[unlimited.ID])
+}
+
 func TestGetAllSchemes(t *testing.T) {
 	router := setupRouter()
 
@@ -140,14 +249,14 @@ func TestGetAllSchemes(t *testing.T) {
 						Criteria: models.Criteria{Rules: []models.Rule{
 							{Field: "income", Operator: "<=", Value: 20000},
 						}},
-						Benefits: json.RawMessage(`{"description": "Provides financial assistance to low-income families.", "amount": 1000}`),
+						Benefits: models.Benefits{Description: "Provides financial assistance to low-income families.", Amount: models.NewMoney(1000)},
 					},
 					{
 						Name: "Housing Assistance",
 						Criteria: models.Criteria{Rules: []models.Rule{
 							{Field: "housing_status", Operator: "==", Value: "rented"},
 						}},
-						Benefits: json.RawMessage(`{"description": "Provides financial aid for housing.", "amount": 1500}`),
+						Benefits: models.Benefits{Description: "Provides financial aid for housing.", Amount: models.NewMoney(1500)},
 					},
 				}
 				db.Create(&schemes)
@@ -167,10 +276,14 @@ func TestGetAllSchemes(t *testing.T) {
 
 			assert.Equal(t, tt.expectedCode, w.Code)
 
-			var response []models.Scheme
+			var response struct {
+				Items []models.Scheme `json:"items"`
+				Total int64           `json:"total"`
+			}
 			err := json.Unmarshal(w.Body.Bytes(), &response)
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expectedCount, len(response))
+			assert.Equal(t, tt.expectedCount, len(response.Items))
+			assert.Equal(t, int64(tt.expectedCount), response.Total)
 		})
 	}
 }
@@ -179,11 +292,12 @@ func TestGetEligibleSchemes(t *testing.T) {
 	router := setupRouter()
 
 	tests := []struct {
-		name          string
-		setupFunc     func() string // Returns the applicant ID
-		expectedCode  int
-		expectedCount int
-		expectedError string
+		name            string
+		setupFunc       func() string // Returns the applicant ID
+		expectedCode    int
+		expectedCount   int
+		expectedSkipped int
+		expectedError   string
 	}{
 		{
 			name: "Eligible schemes found",
@@ -192,7 +306,7 @@ func TestGetEligibleSchemes(t *testing.T) {
 				applicant := models.Applicant{
 					Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
 					DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
-					Income:      15000,
+					Income:      models.NewMoney(15000),
 				}
 				db.Create(&applicant)
 
@@ -201,7 +315,7 @@ func TestGetEligibleSchemes(t *testing.T) {
 					Criteria: models.Criteria{Rules: []models.Rule{
 						{Field: "income", Operator: "<=", Value: 20000},
 					}},
-					Benefits: json.RawMessage(`{"description": "Provides financial assistance to low-income families.", "amount": 1000}`),
+					Benefits: models.Benefits{Description: "Provides financial assistance to low-income families.", Amount: models.NewMoney(1000)},
 				}
 				db.Create(&scheme)
 
@@ -218,7 +332,7 @@ func TestGetEligibleSchemes(t *testing.T) {
 				applicant := models.Applicant{
 					Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
 					DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
-					Income:      50000,
+					Income:      models.NewMoney(50000),
 				}
 				db.Create(&applicant)
 
@@ -227,7 +341,7 @@ func TestGetEligibleSchemes(t *testing.T) {
 					Criteria: models.Criteria{Rules: []models.Rule{
 						{Field: "income", Operator: "<=", Value: 20000},
 					}},
-					Benefits: json.RawMessage(`{"description": "Provides financial assistance to low-income families.", "amount": 1000}`),
+					Benefits: models.Benefits{Description: "Provides financial assistance to low-income families.", Amount: models.NewMoney(1000)},
 				}
 				db.Create(&scheme)
 
@@ -237,6 +351,106 @@ func TestGetEligibleSchemes(t *testing.T) {
 			expectedCount: 0,
 			expectedError: "",
 		},
+		{
+			name: "Eligible through nested any group",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name: "John Doe", EmploymentStatus: "unemployed", Sex: "male",
+					DateOfBirth:   time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC),
+					Income:        models.NewMoney(1500),
+					MaritalStatus: "widowed",
+				}
+				db.Create(&applicant)
+
+				scheme := models.Scheme{
+					Name: "Senior Assistance",
+					Criteria: models.Criteria{
+						Rules: []models.Rule{
+							{Field: "income", Operator: "<", Value: 2000},
+						},
+						Any: []models.Criteria{
+							{Rules: []models.Rule{{Field: "disability_status", Operator: "==", Value: "disabled"}}},
+							{Rules: []models.Rule{{Field: "age", Operator: ">=", Value: 65}}},
+						},
+					},
+					Benefits: models.Benefits{Amount: models.NewMoney(500)},
+				}
+				db.Create(&scheme)
+
+				return applicant.ID.String()
+			},
+			expectedCode:  http.StatusOK,
+			expectedCount: 1,
+			expectedError: "",
+		},
+		{
+			name: "Eligible through household member",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name: "Jane Doe", EmploymentStatus: "employed", Sex: "female",
+					DateOfBirth: time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC),
+					Household: []models.HouseholdMember{
+						{Name: "John Doe", Relation: "spouse", DateOfBirth: time.Date(1984, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "unemployed"},
+					},
+				}
+				db.Create(&applicant)
+
+				scheme := models.Scheme{
+					Name: "Household Support",
+					Criteria: models.Criteria{Rules: []models.Rule{
+						{Field: "household", Quantifier: "any", Member: &models.Criteria{Rules: []models.Rule{
+							{Field: "employment_status", Operator: "==", Value: "unemployed"},
+						}}},
+					}},
+					Benefits: models.Benefits{Amount: models.NewMoney(300)},
+				}
+				db.Create(&scheme)
+
+				return applicant.ID.String()
+			},
+			expectedCode:  http.StatusOK,
+			expectedCount: 1,
+			expectedError: "",
+		},
+		{
+			name: "Misconfigured schemes are skipped",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+					DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+					Income:      models.NewMoney(15000),
+				}
+				db.Create(&applicant)
+
+				schemes := []models.Scheme{
+					{
+						Name: "Low Income Assistance",
+						Criteria: models.Criteria{Rules: []models.Rule{
+							{Field: "income", Operator: "<=", Value: 20000},
+						}},
+						Benefits: models.Benefits{Amount: models.NewMoney(1000)},
+					},
+					{
+						Name: "Broken Assistance",
+						Criteria: models.Criteria{Rules: []models.Rule{
+							{Field: "income", Operator: "<=", Value: "20000"},
+						}},
+						Benefits: models.Benefits{Amount: models.NewMoney(1000)},
+					},
+				}
+				db.Create(&schemes)
+				db.Exec(`INSERT INTO schemes (name, criteria, benefits) VALUES ('Malformed Assistance', '{"rules": {"field": "income"}}', '{}')`)
+
+				return applicant.ID.String()
+			},
+			expectedCode:    http.StatusOK,
+			expectedCount:   1,
+			expectedSkipped: 2,
+			expectedError:   "",
+		},
 		{
 			name: "Applicant not found",
 			setupFunc: func() string {
@@ -261,11 +475,597 @@ func TestGetEligibleSchemes(t *testing.T) {
 			if tt.expectedError != "" {
 				assert.Contains(t, w.Body.String(), tt.expectedError)
 			} else {
-				var response []models.Scheme
+				var response struct {
+					Schemes []utils.EligibleScheme `json:"schemes"`
+					Skipped []utils.SkippedScheme  `json:"skipped"`
+				}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedCount, len(response.Schemes))
+				assert.Equal(t, tt.expectedSkipped, len(response.Skipped))
+				for _, scheme := range response.Schemes {
+					assert.Equal(t, scheme.Benefits.Amount, scheme.Entitlement.Total)
+				}
+			}
+		})
+	}
+}
+
+func TestExplainEligibility(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name             string
+		setupFunc        func() (string, string) // Returns the applicant ID and scheme query
+		expectedCode     int
+		expectedCount    int
+		expectedEligible map[string]bool // Keyed by scheme name
+		expectedError    string
+	}{
+		{
+			name: "Explain all schemes",
+			setupFunc: func() (string, string) {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+					DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+					Income:      models.NewMoney(15000),
+				}
+				db.Create(&applicant)
+
+				schemes := []models.Scheme{
+					{
+						Name: "Low Income Assistance",
+						Criteria: models.Criteria{Rules: []models.Rule{
+							{Field: "income", Operator: "<=", Value: 20000},
+						}},
+						Benefits: models.Benefits{Amount: models.NewMoney(1000)},
+					},
+					{
+						Name: "Retrenchment Assistance",
+						Criteria: models.Criteria{Rules: []models.Rule{
+							{Field: "employment_status", Operator: "==", Value: "unemployed"},
+						}},
+						Benefits: models.Benefits{Amount: models.NewMoney(500)},
+					},
+				}
+				db.Create(&schemes)
+
+				return applicant.ID.String(), ""
+			},
+			expectedCode:     http.StatusOK,
+			expectedCount:    2,
+			expectedEligible: map[string]bool{"Low Income Assistance": true, "Retrenchment Assistance": false},
+		},
+		{
+			name: "Explain single scheme",
+			setupFunc: func() (string, string) {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+					DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+					Income:      models.NewMoney(50000),
+				}
+				db.Create(&applicant)
+
+				scheme := models.Scheme{
+					Name: "Low Income Assistance",
+					Criteria: models.Criteria{Rules: []models.Rule{
+						{Field: "income", Operator: "<=", Value: 20000},
+					}},
+					Benefits: models.Benefits{Amount: models.NewMoney(1000)},
+				}
+				db.Create(&scheme)
+
+				return applicant.ID.String(), scheme.ID.String()
+			},
+			expectedCode:     http.StatusOK,
+			expectedCount:    1,
+			expectedEligible: map[string]bool{"Low Income Assistance": false},
+		},
+		{
+			name: "Scheme not found",
+			setupFunc: func() (string, string) {
+				db := setupTestDB(t)
+				applicant := models.Applicant{
+					Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+					DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+				}
+				db.Create(&applicant)
+
+				return applicant.ID.String(), uuid.NewString()
+			},
+			expectedCode:  http.StatusNotFound,
+			expectedError: "Scheme not found",
+		},
+		{
+			name: "Applicant not found",
+			setupFunc: func() (string, string) {
+				setupTestDB(t)
+				return uuid.NewString(), ""
+			},
+			expectedCode:  http.StatusNotFound,
+			expectedError: "Applicant not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applicantID, schemeID := tt.setupFunc()
+
+			url := "/api/schemes/eligible/explain?applicant=" + applicantID
+			if schemeID != "" {
+				url += "&scheme=" + schemeID
+			}
+			req, _ := http.NewRequest("GET", url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				return
+			}
+
+			var response []utils.SchemeEligibility
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCount, len(response))
+
+			for _, result := range response {
+				assert.Equal(t, tt.expectedEligible[result.SchemeName], result.Eligible)
+				assert.Equal(t, result.Eligible, result.Criteria.Passed)
+				assert.Len(t, result.Criteria.Rules, 1)
+			}
+		})
+	}
+}
+
+func TestExplainAgreesWithEligibleOnMalformedBenefits(t *testing.T) {
+	router := setupRouter()
+	db := setupTestDB(t)
+
+	applicant := models.Applicant{
+		Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+		DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		Income:      models.NewMoney(15000),
+	}
+	db.Create(&applicant)
+	db.Exec(`INSERT INTO schemes (name, criteria, benefits) VALUES ('Legacy Assistance', '{"rules": [{"field": "income", "operator": "<=", "value": 20000}]}', '{"amount": "lots"}')`)
+
+	req, _ := http.NewRequest("GET", "/api/schemes/eligible?applicant="+applicant.ID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var eligible struct {
+		Schemes []utils.EligibleScheme `json:"schemes"`
+		Skipped []utils.SkippedScheme  `json:"skipped"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &eligible))
+	assert.Empty(t, eligible.Schemes)
+	if assert.Len(t, eligible.Skipped, 1) {
+		assert.Equal(t, "benefits", eligible.Skipped[0].Errors[0].Path)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/schemes/eligible/explain?applicant="+applicant.ID.String(), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var explained []utils.SchemeEligibility
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &explained))
+	if assert.Len(t, explained, 1) {
+		assert.False(t, explained[0].Eligible)
+		assert.Equal(t, eligible.Skipped[0].Errors, explained[0].Errors)
+	}
+}
+
+func createTestScheme(t *testing.T, db *gorm.DB) models.Scheme {
+	scheme := models.Scheme{
+		Name: "Low Income Assistance",
+		Criteria: models.Criteria{Rules: []models.Rule{
+			{Field: "income", Operator: "<=", Value: 20000},
+		}},
+		Benefits: models.Benefits{Amount: models.NewMoney(1000)},
+		Version:  1,
+	}
+	if err := db.Create(&scheme).Error; err != nil {
+		t.Fatalf("Failed to create scheme: %v", err)
+	}
+	db.Create(&models.SchemeVersion{SchemeID: scheme.ID, Version: 1, Criteria: scheme.Criteria, Benefits: scheme.Benefits})
+	return scheme
+}
+
+func TestGetSchemeByID(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name          string
+		setupFunc     func() string // Returns the ID of the scheme to fetch
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name: "Scheme exists",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestScheme(t, db).ID.String()
+			},
+			expectedCode:  http.StatusOK,
+			expectedError: "",
+		},
+		{
+			name: "Scheme not found",
+			setupFunc: func() string {
+				setupTestDB(t)
+				return uuid.NewString()
+			},
+			expectedCode:  http.StatusNotFound,
+			expectedError: "scheme not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemeID := tt.setupFunc()
+
+			req, _ := http.NewRequest("GET", "/api/schemes/"+schemeID, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			} else {
+				var response models.Scheme
 				err := json.Unmarshal(w.Body.Bytes(), &response)
 				assert.NoError(t, err)
-				assert.Equal(t, tt.expectedCount, len(response))
+				assert.Equal(t, schemeID, response.ID.String())
+			}
+		})
+	}
+}
+
+func TestUpdateScheme(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name            string
+		setupFunc       func() string // Returns the ID of the scheme to update
+		inputJSON       string
+		expectedCode    int
+		expectedVersion int
+		expectedError   string
+	}{
+		{
+			name: "Criteria change records a new version",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestScheme(t, db).ID.String()
+			},
+			inputJSON:       `{"criteria": {"rules": [{"field": "income", "operator": "<=", "value": 15000}]}}`,
+			expectedCode:    http.StatusOK,
+			expectedVersion: 2,
+		},
+		{
+			name: "Name change keeps the current version",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestScheme(t, db).ID.String()
+			},
+			inputJSON:       `{"name": "Renamed Assistance"}`,
+			expectedCode:    http.StatusOK,
+			expectedVersion: 1,
+		},
+		{
+			name: "Limits change keeps the current version",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestScheme(t, db).ID.String()
+			},
+			inputJSON:       `{"opens_at": "2024-01-01T00:00:00Z", "closes_at": null, "max_beneficiaries": 100, "budget": 50000}`,
+			expectedCode:    http.StatusOK,
+			expectedVersion: 1,
+		},
+		{
+			name: "Window closing before it opens",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				scheme := createTestScheme(t, db)
+				opensAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+				db.Model(&scheme).Update("opens_at", opensAt)
+				return scheme.ID.String()
+			},
+			inputJSON:     `{"closes_at": "2024-01-01T00:00:00Z"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "closes_at: must be after opens_at",
+		},
+		{
+			name: "Scheme not found",
+			setupFunc: func() string {
+				setupTestDB(t)
+				return uuid.NewString()
+			},
+			inputJSON:     `{"name": "Renamed Assistance"}`,
+			expectedCode:  http.StatusNotFound,
+			expectedError: "scheme not found",
+		},
+		{
+			name: "Invalid input",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestScheme(t, db).ID.String()
+			},
+			inputJSON:     `{"criteria": "invalid-criteria-format"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "json: cannot unmarshal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemeID := tt.setupFunc()
+
+			req, _ := http.NewRequest("PUT", "/api/schemes/"+schemeID, strings.NewReader(tt.inputJSON))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				return
+			}
+
+			assert.Contains(t, w.Body.String(), "scheme updated successfully")
+
+			var versions []models.SchemeVersion
+			db.DB.Where("scheme_id = ?", schemeID).Order("version").Find(&versions)
+			assert.Equal(t, tt.expectedVersion, len(versions))
+			assert.Equal(t, tt.expectedVersion, versions[len(versions)-1].Version)
+		})
+	}
+}
+
+func TestDeleteScheme(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name          string
+		setupFunc     func() string // Returns the ID of the scheme to delete
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name: "Valid delete",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				return createTestScheme(t, db).ID.String()
+			},
+			expectedCode:  http.StatusOK,
+			expectedError: "",
+		},
+		{
+			name: "Scheme with applications",
+			setupFunc: func() string {
+				db := setupTestDB(t)
+				scheme := createTestScheme(t, db)
+				db.Create(&models.Application{ApplicantID: uuid.New(), SchemeID: scheme.ID, Status: models.ApplicationSubmitted})
+				return scheme.ID.String()
+			},
+			expectedCode:  http.StatusConflict,
+			expectedError: "scheme has existing applications",
+		},
+		{
+			name: "Scheme not found",
+			setupFunc: func() string {
+				setupTestDB(t)
+				return uuid.NewString()
+			},
+			expectedCode:  http.StatusNotFound,
+			expectedError: "scheme not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemeID := tt.setupFunc()
+
+			req, _ := http.NewRequest("DELETE", "/api/schemes/"+schemeID, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			} else {
+				assert.Contains(t, w.Body.String(), "scheme deleted successfully")
 			}
 		})
 	}
 }
+
+func TestGetSchemeVersions(t *testing.T) {
+	router := setupRouter()
+
+	db := setupTestDB(t)
+	scheme := createTestScheme(t, db)
+
+	req, _ := http.NewRequest("PUT", "/api/schemes/"+scheme.ID.String(), strings.NewReader(`{"benefits": {"amount": 1200}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/schemes/"+scheme.ID.String()+"/versions", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Items []models.SchemeVersion `json:"items"`
+		Total int64                  `json:"total"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.Total)
+	if assert.Len(t, response.Items, 2) {
+		assert.Equal(t, models.NewMoney(1000), response.Items[0].Benefits.Amount)
+		assert.Equal(t, models.NewMoney(1200), response.Items[1].Benefits.Amount)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/schemes/"+scheme.ID.String()+"/versions?sort=-version&page_size=1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	response.Items = nil
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.Total)
+	if assert.Len(t, response.Items, 1) {
+		assert.Equal(t, 2, response.Items[0].Version)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/schemes/"+scheme.ID.String()+"/versions?sort=benefits", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestValidateScheme(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name           string
+		inputJSON      string
+		expectedCode   int
+		expectedValid  bool
+		expectedErrors []string
+	}{
+		{
+			name:           "Valid criteria",
+			inputJSON:      `{"name": "Low Income Assistance", "criteria": {"rules": [{"field": "income", "operator": "<=", "value": 20000}]}}`,
+			expectedCode:   http.StatusOK,
+			expectedValid:  true,
+			expectedErrors: []string{},
+		},
+		{
+			name:          "Invalid criteria",
+			inputJSON:     `{"name": "Low Income Assistance", "criteria": {"rules": [{"field": "income", "operator": "~", "value": 20000}, {"field": "salary", "operator": "==", "value": 1}]}}`,
+			expectedCode:  http.StatusOK,
+			expectedValid: false,
+			expectedErrors: []string{
+				"rules[0].operator",
+				"rules[1].field",
+			},
+		},
+		{
+			name:         "Invalid JSON input",
+			inputJSON:    `{"criteria": "invalid-criteria-format"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/schemes/validate", strings.NewReader(tt.inputJSON))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Valid  bool                  `json:"valid"`
+				Errors []utils.CriteriaError `json:"errors"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedValid, response.Valid)
+
+			paths := make([]string, 0, len(response.Errors))
+			for _, e := range response.Errors {
+				paths = append(paths, e.Path)
+			}
+			assert.Equal(t, tt.expectedErrors, paths)
+		})
+	}
+}
+
+func TestGetEligibleSchemesAsOf(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name          string
+		asOf          string
+		expectedCode  int
+		expectedCount int
+		expectedError string
+	}{
+		{
+			name:          "Not yet eligible",
+			asOf:          "2064-12-31",
+			expectedCode:  http.StatusOK,
+			expectedCount: 0,
+		},
+		{
+			name:          "Eligible on 65th birthday",
+			asOf:          "2065-01-01",
+			expectedCode:  http.StatusOK,
+			expectedCount: 1,
+		},
+		{
+			name:          "Invalid as_of date",
+			asOf:          "01-01-2065",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid as_of date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			applicant := models.Applicant{
+				Name: "John Doe", EmploymentStatus: "employed", Sex: "male",
+				DateOfBirth: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			}
+			db.Create(&applicant)
+
+			scheme := models.Scheme{
+				Name: "Senior Assistance",
+				Criteria: models.Criteria{Rules: []models.Rule{
+					{Field: "age", Operator: ">=", Value: 65},
+				}},
+				Benefits: models.Benefits{Amount: models.NewMoney(500)},
+			}
+			db.Create(&scheme)
+
+			req, _ := http.NewRequest("GET", "/api/schemes/eligible?applicant="+applicant.ID.String()+"&as_of="+tt.asOf, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				return
+			}
+
+			var response struct {
+				AsOf    string          `json:"as_of"`
+				Schemes []models.Scheme `json:"schemes"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.asOf, response.AsOf)
+			assert.Equal(t, tt.expectedCount, len(response.Schemes))
+		})
+	}
+}
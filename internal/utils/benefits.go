@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+)
+
+// childRelations are the household relations counted for per-child benefits.
+var childRelations = []string{"son", "daughter", "child"}
+
+// CalculateEntitlement computes the benefits an applicant whose household has
+// already been loaded receives from a scheme, with children's ages taken as of asOf.
+func CalculateEntitlement(benefits models.Benefits, applicant models.Applicant, asOf time.Time) models.Entitlement {
+	entitlement := models.Entitlement{
+		OneOff:   benefits.Amount,
+		Vouchers: make([]models.Voucher, 0, len(benefits.Vouchers)),
+	}
+
+	if benefits.PerChild != nil {
+		entitlement.Children = countChildren(applicant.Household, *benefits.PerChild, asOf)
+		entitlement.OneOff = entitlement.OneOff.Add(benefits.PerChild.Amount.Mul(entitlement.Children))
+	}
+
+	if benefits.Monthly != nil {
+		entitlement.Monthly = benefits.Monthly.Amount
+		entitlement.Months = benefits.Monthly.Months
+	}
+
+	entitlement.Vouchers = append(entitlement.Vouchers, benefits.Vouchers...)
+
+	entitlement.Total = entitlement.OneOff.Add(entitlement.Monthly.Mul(entitlement.Months))
+	if benefits.Cap.Amount > 0 && entitlement.Total.Amount > benefits.Cap.Amount {
+		entitlement.Total = benefits.Cap
+		entitlement.Capped = true
+	}
+
+	return entitlement
+}
+
+func countChildren(household []models.HouseholdMember, perChild models.PerChild, asOf time.Time) int {
+	children := 0
+	for _, member := range household {
+		if !contains(childRelations, member.Relation) {
+			continue
+		}
+		if perChild.MaxAge > 0 && calculateAge(member.DateOfBirth, asOf) >= perChild.MaxAge {
+			continue
+		}
+		children++
+	}
+
+	if perChild.MaxChildren > 0 && children > perChild.MaxChildren {
+		return perChild.MaxChildren
+	}
+	return children
+}
+
+// ValidateBenefits checks that a scheme's benefits can be calculated. Paths are
+// relative to the benefits object, e.g. "monthly.months".
+func ValidateBenefits(benefits models.Benefits) []CriteriaError {
+	errs := make([]CriteriaError, 0)
+
+	errs = checkMoney("amount", benefits.Amount, false, errs)
+	errs = checkMoney("cap", benefits.Cap, false, errs)
+
+	if benefits.PerChild != nil {
+		errs = checkMoney("per_child.amount", benefits.PerChild.Amount, true, errs)
+		if benefits.PerChild.MaxAge < 0 {
+			errs = append(errs, CriteriaError{Path: "per_child.max_age", Message: "must not be negative"})
+		}
+		if benefits.PerChild.MaxChildren < 0 {
+			errs = append(errs, CriteriaError{Path: "per_child.max_children", Message: "must not be negative"})
+		}
+	}
+
+	if benefits.Monthly != nil {
+		errs = checkMoney("monthly.amount", benefits.Monthly.Amount, true, errs)
+		if benefits.Monthly.Months <= 0 {
+			errs = append(errs, CriteriaError{Path: "monthly.months", Message: "must be positive"})
+		}
+	}
+
+	for i, voucher := range benefits.Vouchers {
+		path := fmt.Sprintf("vouchers[%d]", i)
+		if voucher.Type == "" {
+			errs = append(errs, CriteriaError{Path: joinPath(path, "type"), Message: "is required"})
+		}
+		errs = checkMoney(joinPath(path, "value"), voucher.Value, true, errs)
+		if voucher.Quantity <= 0 {
+			errs = append(errs, CriteriaError{Path: joinPath(path, "quantity"), Message: "must be positive"})
+		}
+	}
+
+	return errs
+}
+
+// checkMoney checks that an amount is in the default currency and is not
+// negative, or is positive when required.
+func checkMoney(path string, amount models.Money, positive bool, errs []CriteriaError) []CriteriaError {
+	if amount.CurrencyCode() != models.DefaultCurrency {
+		return append(errs, CriteriaError{
+			Path:    path,
+			Message: fmt.Sprintf("unsupported currency %q, expected %s", amount.Currency, models.DefaultCurrency),
+		})
+	}
+	if positive && amount.Amount <= 0 {
+		return append(errs, CriteriaError{Path: path, Message: "must be positive"})
+	}
+	if amount.Amount < 0 {
+		return append(errs, CriteriaError{Path: path, Message: "must not be negative"})
+	}
+	return errs
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateEntitlement(t *testing.T) {
+	asOf := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	applicant := models.Applicant{
+		Name: "Jane Doe",
+		Household: []models.HouseholdMember{
+			{Name: "Jimmy Doe", Relation: "son", DateOfBirth: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Name: "Jenny Doe", Relation: "daughter", DateOfBirth: time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Name: "John Doe", Relation: "spouse", DateOfBirth: time.Date(1984, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		benefits string
+		expected models.Entitlement
+	}{
+		{
+			name:     "One-off amount",
+			benefits: `{"amount": 500}`,
+			expected: models.Entitlement{OneOff: models.NewMoney(500), Vouchers: []models.Voucher{}, Total: models.NewMoney(500)},
+		},
+		{
+			name:     "Per-child amount counts children only",
+			benefits: `{"amount": 500, "per_child": {"amount": 100}}`,
+			expected: models.Entitlement{OneOff: models.NewMoney(700), Children: 2, Vouchers: []models.Voucher{}, Total: models.NewMoney(700)},
+		},
+		{
+			name:     "Per-child amount with max age",
+			benefits: `{"per_child": {"amount": 100, "max_age": 12}}`,
+			expected: models.Entitlement{OneOff: models.NewMoney(100), Children: 1, Vouchers: []models.Voucher{}, Total: models.NewMoney(100)},
+		},
+		{
+			name:     "Per-child amount with max children",
+			benefits: `{"per_child": {"amount": 100, "max_children": 1}}`,
+			expected: models.Entitlement{OneOff: models.NewMoney(100), Children: 1, Vouchers: []models.Voucher{}, Total: models.NewMoney(100)},
+		},
+		{
+			name:     "Monthly payout",
+			benefits: `{"amount": 200, "monthly": {"amount": 300, "months": 6}}`,
+			expected: models.Entitlement{OneOff: models.NewMoney(200), Monthly: models.NewMoney(300), Months: 6, Vouchers: []models.Voucher{}, Total: models.NewMoney(2000)},
+		},
+		{
+			name:     "Cap limits total",
+			benefits: `{"amount": 200, "monthly": {"amount": 300, "months": 6}, "cap": 1500}`,
+			expected: models.Entitlement{OneOff: models.NewMoney(200), Monthly: models.NewMoney(300), Months: 6, Vouchers: []models.Voucher{}, Total: models.NewMoney(1500), Capped: true},
+		},
+		{
+			name:     "Vouchers are passed through",
+			benefits: `{"vouchers": [{"type": "CDC", "value": 50, "quantity": 2}]}`,
+			expected: models.Entitlement{Vouchers: []models.Voucher{{Type: "CDC", Value: models.NewMoney(50), Quantity: 2}}, Total: models.NewMoney(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var benefits models.Benefits
+			err := json.Unmarshal([]byte(tt.benefits), &benefits)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, CalculateEntitlement(benefits, applicant, asOf))
+		})
+	}
+}
+
+func TestValidateBenefits(t *testing.T) {
+	tests := []struct {
+		name           string
+		benefits       string
+		expectedErrors []string
+	}{
+		{
+			name:           "Valid benefits",
+			benefits:       `{"amount": 500, "per_child": {"amount": 100, "max_age": 18}, "monthly": {"amount": 300, "months": 6}, "vouchers": [{"type": "CDC", "value": 50, "quantity": 2}], "cap": 3000}`,
+			expectedErrors: []string{},
+		},
+		{
+			name:           "Empty benefits",
+			benefits:       `{}`,
+			expectedErrors: []string{},
+		},
+		{
+			name:           "Negative amounts",
+			benefits:       `{"amount": -1, "cap": -1}`,
+			expectedErrors: []string{"amount: must not be negative", "cap: must not be negative"},
+		},
+		{
+			name:           "Unsupported currency",
+			benefits:       `{"amount": {"amount": 500, "currency": "USD"}, "cap": {"amount": 1000, "currency": "SGD"}}`,
+			expectedErrors: []string{`amount: unsupported currency "USD", expected SGD`},
+		},
+		{
+			name:           "Invalid per-child",
+			benefits:       `{"per_child": {"amount": 0, "max_age": -1}}`,
+			expectedErrors: []string{"per_child.amount: must be positive", "per_child.max_age: must not be negative"},
+		},
+		{
+			name:           "Monthly payout without months",
+			benefits:       `{"monthly": {"amount": 300}}`,
+			expectedErrors: []string{"monthly.months: must be positive"},
+		},
+		{
+			name:           "Invalid voucher",
+			benefits:       `{"vouchers": [{"type": "CDC", "value": 50, "quantity": 1}, {"value": 0, "quantity": 1}]}`,
+			expectedErrors: []string{"vouchers[1].type: is required", "vouchers[1].value: must be positive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var benefits models.Benefits
+			err := json.Unmarshal([]byte(tt.benefits), &benefits)
+			assert.NoError(t, err)
+
+			errs := ValidateBenefits(benefits)
+			messages := make([]string, 0, len(errs))
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			assert.Equal(t, tt.expectedErrors, messages)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SchemeCapacity is how much of a scheme's beneficiary quota and budget has
+// been granted. The remaining values are nil when the scheme has no limit.
+type SchemeCapacity struct {
+	Beneficiaries          int           `json:"beneficiaries"`
+	RemainingBeneficiaries *int          `json:"remaining_beneficiaries"`
+	BudgetUsed             models.Money  `json:"budget_used"`
+	RemainingBudget        *models.Money `json:"remaining_budget"`
+}
+
+// Full reports whether the scheme can take no more beneficiaries.
+func (c SchemeCapacity) Full() bool {
+	return (c.RemainingBeneficiaries != nil && *c.RemainingBeneficiaries <= 0) ||
+		(c.RemainingBudget != nil && c.RemainingBudget.Amount <= 0)
+}
+
+// CanGrant reports whether an entitlement of the given total fits within the
+// scheme's remaining capacity.
+func (c SchemeCapacity) CanGrant(total models.Money) bool {
+	if c.RemainingBeneficiaries != nil && *c.RemainingBeneficiaries <= 0 {
+		return false
+	}
+	return c.RemainingBudget == nil || total.Amount <= c.RemainingBudget.Amount
+}
+
+// IsSchemeOpen reports whether the scheme accepts applications at the given time.
+func IsSchemeOpen(scheme models.Scheme, at time.Time) bool {
+	if scheme.OpensAt != nil && at.Before(*scheme.OpensAt) {
+		return false
+	}
+	return scheme.ClosesAt == nil || at.Before(*scheme.ClosesAt)
+}
+
+// GetSchemeCapacity returns the scheme's capacity from its granted applications.
+// Callers enforcing the limits should hold a lock on the scheme's row.
+func GetSchemeCapacity(tx *gorm.DB, scheme models.Scheme) (SchemeCapacity, error) {
+	capacities, err := GetSchemeCapacities(tx, []models.Scheme{scheme})
+	if err != nil {
+		return SchemeCapacity{}, err
+	}
+	return capacities[scheme.ID], nil
+}
+
+// GetSchemeCapacities returns the capacity of each of the schemes, keyed by ID.
+func GetSchemeCapacities(tx *gorm.DB, schemes []models.Scheme) (map[uuid.UUID]SchemeCapacity, error) {
+	ids := make([]uuid.UUID, 0, len(schemes))
+	byID := make(map[uuid.UUID]models.Scheme, len(schemes))
+	for _, scheme := range schemes {
+		ids = append(ids, scheme.ID)
+		byID[scheme.ID] = scheme
+	}
+
+	var granted []struct {
+		SchemeID      uuid.UUID
+		Beneficiaries int
+		BudgetUsed    int64
+	}
+	if len(ids) > 0 {
+		err := tx.Model(&models.Application{}).
+			Select("scheme_id, COUNT(*) AS beneficiaries, COALESCE(SUM((entitlement->'total'->>'amount')::bigint), 0) AS budget_used").
+			Where("scheme_id IN ? AND status IN ?", ids, models.GrantedApplicationStatuses).
+			Group("scheme_id").
+			Scan(&granted).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	capacities := make(map[uuid.UUID]SchemeCapacity, len(schemes))
+	for _, scheme := range schemes {
+		capacities[scheme.ID] = newSchemeCapacity(scheme, 0, models.NewMoney(0))
+	}
+	for _, row := range granted {
+		capacities[row.SchemeID] = newSchemeCapacity(byID[row.SchemeID], row.Beneficiaries, models.NewMoney(row.BudgetUsed))
+	}
+	return capacities, nil
+}
+
+func newSchemeCapacity(scheme models.Scheme, beneficiaries int, budgetUsed models.Money) SchemeCapacity {
+	capacity := SchemeCapacity{Beneficiaries: beneficiaries, BudgetUsed: budgetUsed}
+	if scheme.MaxBeneficiaries > 0 {
+		remaining := scheme.MaxBeneficiaries - beneficiaries
+		capacity.RemainingBeneficiaries = &remaining
+	}
+	if scheme.Budget.Amount > 0 {
+		remaining := scheme.Budget.Sub(budgetUsed)
+		capacity.RemainingBudget = &remaining
+	}
+	return capacity
+}
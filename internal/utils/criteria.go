@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+)
+
+type fieldType int
+
+const (
+	numberField fieldType = iota
+	stringField
+	dateField
+	householdField
+	moneyField
+)
+
+// applicantFields lists the fields a scheme rule may reference and their types.
+// Incomes are compared per month.
+var applicantFields = map[string]fieldType{
+	"income":             moneyField,
+	"employment_status":  stringField,
+	"age":                numberField,
+	"date_of_birth":      dateField,
+	"marital_status":     stringField,
+	"disability_status":  stringField,
+	"number_of_children": numberField,
+	"last_employed":      dateField,
+	"household_size":     numberField,
+	"household_income":   moneyField,
+	"per_capita_income":  moneyField,
+	"household":          householdField,
+}
+
+// memberFields lists the fields a household rule's member predicate may reference.
+var memberFields = map[string]fieldType{
+	"relation":          stringField,
+	"age":               numberField,
+	"date_of_birth":     dateField,
+	"employment_status": stringField,
+	"income":            moneyField,
+}
+
+var quantifiers = []string{"any", "all", "count"}
+
+// CriteriaError describes a problem with a single element of a scheme's criteria.
+// Path addresses the element within the criteria, e.g. "rules[2].value".
+type CriteriaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e CriteriaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidateCriteria checks that every rule in the criteria references a known
+// field with an operator and value the eligibility engine supports. It returns
+// all problems found rather than stopping at the first.
+func ValidateCriteria(criteria models.Criteria) []CriteriaError {
+	errs := make([]CriteriaError, 0)
+	return validateCriteria(criteria, "", applicantFields, errs)
+}
+
+func validateCriteria(criteria models.Criteria, path string, fields map[string]fieldType, errs []CriteriaError) []CriteriaError {
+	for i, rule := range criteria.Rules {
+		errs = validateRule(rule, joinPath(path, fmt.Sprintf("rules[%d]", i)), fields, errs)
+	}
+	for i, group := range criteria.All {
+		errs = validateCriteria(group, joinPath(path, fmt.Sprintf("all[%d]", i)), fields, errs)
+	}
+	for i, group := range criteria.Any {
+		errs = validateCriteria(group, joinPath(path, fmt.Sprintf("any[%d]", i)), fields, errs)
+	}
+	if criteria.Not != nil {
+		errs = validateCriteria(*criteria.Not, joinPath(path, "not"), fields, errs)
+	}
+	return errs
+}
+
+func validateRule(rule models.Rule, path string, fields map[string]fieldType, errs []CriteriaError) []CriteriaError {
+	if rule.Field == "" {
+		return append(errs, CriteriaError{Path: joinPath(path, "field"), Message: "is required"})
+	}
+
+	fieldType, ok := fields[rule.Field]
+	if !ok {
+		return append(errs, CriteriaError{Path: joinPath(path, "field"), Message: fmt.Sprintf("unknown field %q", rule.Field)})
+	}
+
+	if fieldType == householdField {
+		return validateHouseholdRule(rule, path, errs)
+	}
+
+	if rule.Quantifier != "" {
+		errs = append(errs, CriteriaError{Path: joinPath(path, "quantifier"), Message: "is only supported on household rules"})
+	}
+	if rule.Member != nil {
+		errs = append(errs, CriteriaError{Path: joinPath(path, "member"), Message: "is only supported on household rules"})
+	}
+
+	if rule.Elapsed != "" {
+		if fieldType != dateField {
+			errs = append(errs, CriteriaError{Path: joinPath(path, "elapsed"), Message: "is only supported on date rules"})
+		} else if !contains(elapsedUnits, rule.Elapsed) {
+			// The operator cannot be checked without knowing what is compared.
+			return append(errs, CriteriaError{
+				Path:    joinPath(path, "elapsed"),
+				Message: fmt.Sprintf("expected one of %s", strings.Join(elapsedUnits, ", ")),
+			})
+		} else {
+			// The elapsed duration is compared as a number.
+			fieldType = numberField
+		}
+	}
+
+	return validateComparison(rule, path, fieldType, errs)
+}
+
+func validateHouseholdRule(rule models.Rule, path string, errs []CriteriaError) []CriteriaError {
+	if !contains(quantifiers, rule.Quantifier) {
+		errs = append(errs, CriteriaError{
+			Path:    joinPath(path, "quantifier"),
+			Message: fmt.Sprintf("expected one of %s", strings.Join(quantifiers, ", ")),
+		})
+	}
+
+	if rule.Quantifier == "count" {
+		errs = validateComparison(rule, path, numberField, errs)
+	}
+
+	if rule.Member != nil {
+		errs = validateCriteria(*rule.Member, joinPath(path, "member"), memberFields, errs)
+	}
+
+	return errs
+}
+
+func validateComparison(rule models.Rule, path string, fieldType fieldType, errs []CriteriaError) []CriteriaError {
+	if rule.IgnoreCase && fieldType != stringField {
+		errs = append(errs, CriteriaError{Path: joinPath(path, "ignore_case"), Message: "is only supported on string rules"})
+	}
+
+	if err := checkOperator(fieldType, rule.Operator); err != nil {
+		return append(errs, prefixErrors(path, []CriteriaError{*err})...)
+	}
+	if err := checkOperands(rule, fieldType); err != nil {
+		errs = append(errs, prefixErrors(path, []CriteriaError{*err})...)
+	} else if fieldType == stringField {
+		errs = append(errs, prefixErrors(path, checkVocabulary(rule))...)
+	}
+
+	return errs
+}
+
+// checkVocabulary checks that the values a string rule compares against are in
+// the field's controlled vocabulary, so a misspelt value cannot silently match
+// nobody.
+func checkVocabulary(rule models.Rule) []CriteriaError {
+	vocabulary := Vocabulary(rule.Field)
+	if vocabulary == nil || rule.Operator == "regex" || rule.Operator == "exists" {
+		return nil
+	}
+
+	operands, _ := stringOperands(rule)
+	var errs []CriteriaError
+	for i, operand := range operands {
+		value := operand
+		if rule.IgnoreCase {
+			value = strings.ToLower(value)
+		}
+		if contains(vocabulary, value) {
+			continue
+		}
+		path := "value"
+		if rule.Operator == "in" || rule.Operator == "not_in" {
+			path = fmt.Sprintf("value[%d]", i)
+		}
+		errs = append(errs, CriteriaError{
+			Path:    path,
+			Message: fmt.Sprintf("unknown %s %q, expected one of %s", rule.Field, operand, strings.Join(vocabulary, ", ")),
+		})
+	}
+	return errs
+}
+
+func joinPath(path, element string) string {
+	if path == "" {
+		return element
+	}
+	return path + "." + element
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
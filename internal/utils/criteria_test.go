@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCriteria(t *testing.T) {
+	tests := []struct {
+		name           string
+		criteria       string
+		expectedErrors []string
+	}{
+		{
+			name:           "Valid flat rules",
+			criteria:       `{"rules": [{"field": "income", "operator": "<=", "value": 2000}, {"field": "marital_status", "operator": "==", "value": "widowed"}]}`,
+			expectedErrors: []string{},
+		},
+		{
+			name:           "Empty criteria",
+			criteria:       `{"rules": []}`,
+			expectedErrors: []string{},
+		},
+		{
+			name:           "Unknown field",
+			criteria:       `{"rules": [{"field": "housing_status", "operator": "==", "value": "rented"}]}`,
+			expectedErrors: []string{`rules[0].field: unknown field "housing_status"`},
+		},
+		{
+			name:           "Missing field",
+			criteria:       `{"rules": [{"operator": "==", "value": "rented"}]}`,
+			expectedErrors: []string{"rules[0].field: is required"},
+		},
+		{
+			name:           "Unsupported operator",
+			criteria:       `{"rules": [{"field": "employment_status", "operator": ">", "value": "employed"}]}`,
+			expectedErrors: []string{`rules[0].operator: unsupported operator ">", expected one of ==, !=, in, not_in, regex, exists`},
+		},
+		{
+			name: "String value for number field",
+			criteria: `{"rules": [
+				{"field": "income", "operator": "<=", "value": 2000},
+				{"field": "age", "operator": ">=", "value": 65},
+				{"field": "income", "operator": "<=", "value": "2000"}
+			]}`,
+			expectedErrors: []string{"rules[2].value: expected number of minor units or {amount, currency}"},
+		},
+		{
+			name:           "Fractional value for number field",
+			criteria:       `{"rules": [{"field": "age", "operator": ">=", "value": 64.5}]}`,
+			expectedErrors: []string{"rules[0].value: expected whole number"},
+		},
+		{
+			name:           "Number value for string field",
+			criteria:       `{"rules": [{"field": "employment_status", "operator": "==", "value": 1}]}`,
+			expectedErrors: []string{"rules[0].value: expected string"},
+		},
+		{
+			name: "Errors inside nested groups",
+			criteria: `{"any": [
+				{"rules": [{"field": "age", "operator": ">=", "value": 65}]},
+				{"all": [{"rules": [{"field": "disability_status", "operator": "==", "value": null}]}]}
+			], "not": {"rules": [{"field": "salary", "operator": "<", "value": 1}]}}`,
+			expectedErrors: []string{
+				"any[1].all[0].rules[0].value: expected string",
+				`not.rules[0].field: unknown field "salary"`,
+			},
+		},
+		{
+			name: "Valid household rule",
+			criteria: `{"rules": [{"field": "household", "quantifier": "count", "operator": ">=", "value": 2, "member": {"rules": [
+				{"field": "relation", "operator": "==", "value": "son"}
+			]}}]}`,
+			expectedErrors: []string{},
+		},
+		{
+			name: "Invalid household rule",
+			criteria: `{"rules": [{"field": "household", "quantifier": "most", "member": {"rules": [
+				{"field": "number_of_children", "operator": "<", "value": 1}
+			]}}]}`,
+			expectedErrors: []string{
+				"rules[0].quantifier: expected one of any, all, count",
+				`rules[0].member.rules[0].field: unknown field "number_of_children"`,
+			},
+		},
+		{
+			name:     "Household count without value",
+			criteria: `{"rules": [{"field": "household", "quantifier": "count", "operator": ">="}]}`,
+			expectedErrors: []string{
+				"rules[0].value: expected number",
+			},
+		},
+		{
+			name: "Valid extended operators",
+			criteria: `{"rules": [
+				{"field": "employment_status", "operator": "in", "value": ["Unemployed", "retired"], "ignore_case": true},
+				{"field": "age", "operator": "between", "value": [18, 64]},
+				{"field": "last_employed", "operator": "exists"},
+				{"field": "marital_status", "operator": "regex", "value": "^(widow|divorc)"},
+				{"field": "income", "operator": "!=", "value": 0}
+			]}`,
+			expectedErrors: []string{},
+		},
+		{
+			name: "Money values",
+			criteria: `{"rules": [
+				{"field": "income", "operator": "<=", "value": {"amount": 150000, "currency": "SGD"}},
+				{"field": "household_income", "operator": "between", "value": [0, {"amount": 400000}]},
+				{"field": "per_capita_income", "operator": "<", "value": {"amount": 100000, "currency": "USD"}},
+				{"field": "income", "operator": "in", "value": [0, 1500.5]},
+				{"field": "household", "quantifier": "any", "member": {"rules": [
+					{"field": "income", "operator": ">", "value": "2000"}
+				]}}
+			]}`,
+			expectedErrors: []string{
+				"rules[2].value: expected amount in SGD, got USD",
+				"rules[3].value[1]: expected whole number of minor units",
+				"rules[4].member.rules[0].value: expected number of minor units or {amount, currency}",
+			},
+		},
+		{
+			name: "Invalid extended operator values",
+			criteria: `{"rules": [
+				{"field": "employment_status", "operator": "in", "value": "unemployed"},
+				{"field": "age", "operator": "between", "value": [64, 18]},
+				{"field": "age", "operator": "not_in", "value": [18, "64"]},
+				{"field": "last_employed", "operator": "exists", "value": "yes"},
+				{"field": "marital_status", "operator": "regex", "value": "("},
+				{"field": "last_employed", "operator": "==", "value": "2024-01-01"},
+				{"field": "income", "operator": "<", "value": 100, "ignore_case": true}
+			]}`,
+			expectedErrors: []string{
+				"rules[0].value: expected array of strings",
+				"rules[1].value: min must not be greater than max",
+				"rules[2].value[1]: expected number",
+				"rules[3].value: expected boolean",
+				"rules[4].value: invalid regular expression: error parsing regexp: missing closing ): `(`",
+				`rules[5].operator: unsupported operator "==", expected one of before, after, on, between, exists`,
+				"rules[6].ignore_case: is only supported on string rules",
+			},
+		},
+		{
+			name: "Values outside the controlled vocabulary",
+			criteria: `{"rules": [
+				{"field": "employment_status", "operator": "==", "value": "unemploy"},
+				{"field": "marital_status", "operator": "not_in", "value": ["single", "Widowed"]},
+				{"field": "marital_status", "operator": "==", "value": "Widowed", "ignore_case": true},
+				{"field": "household", "quantifier": "any", "member": {"rules": [{"field": "relation", "operator": "==", "value": "cousin"}]}}
+			]}`,
+			expectedErrors: []string{
+				`rules[0].value: unknown employment_status "unemploy", expected one of employed, self_employed, unemployed, retired, homemaker, preschool, primary_school, secondary_school, tertiary`,
+				`rules[1].value[1]: unknown marital_status "Widowed", expected one of single, married, divorced, separated, widowed`,
+				`rules[3].member.rules[0].value: unknown relation "cousin", expected one of spouse, son, daughter, child, father, mother, sibling, grandparent, grandchild, other`,
+			},
+		},
+		{
+			name: "Valid date rules",
+			criteria: `{"rules": [
+				{"field": "last_employed", "elapsed": "months", "operator": ">=", "value": 6},
+				{"field": "date_of_birth", "operator": "before", "value": "1960-01-01"},
+				{"field": "date_of_birth", "operator": "between", "value": ["1950-01-01", "1959-12-31"]},
+				{"field": "household", "quantifier": "any", "member": {"rules": [{"field": "date_of_birth", "elapsed": "years", "operator": "<", "value": 7}]}}
+			]}`,
+			expectedErrors: []string{},
+		},
+		{
+			name: "Invalid date rules",
+			criteria: `{"rules": [
+				{"field": "last_employed", "elapsed": "weeks", "operator": ">=", "value": 6},
+				{"field": "income", "elapsed": "months", "operator": ">=", "value": 6},
+				{"field": "date_of_birth", "operator": "before", "value": "01-01-1960"},
+				{"field": "date_of_birth", "operator": "between", "value": ["1960-01-01", "1950-01-01"]},
+				{"field": "last_employed", "elapsed": "months", "operator": "before", "value": 6}
+			]}`,
+			expectedErrors: []string{
+				"rules[0].elapsed: expected one of days, months, years",
+				"rules[1].elapsed: is only supported on date rules",
+				"rules[2].value: expected date in YYYY-MM-DD format",
+				"rules[3].value: from must not be after to",
+				`rules[4].operator: unsupported operator "before", expected one of ==, !=, >=, <=, >, <, in, not_in, between, exists`,
+			},
+		},
+		{
+			name:     "Quantifier on non-household rule",
+			criteria: `{"rules": [{"field": "age", "operator": ">=", "value": 65, "quantifier": "any"}]}`,
+			expectedErrors: []string{
+				"rules[0].quantifier: is only supported on household rules",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var criteria models.Criteria
+			err := json.Unmarshal([]byte(tt.criteria), &criteria)
+			assert.NoError(t, err)
+
+			errs := ValidateCriteria(criteria)
+			messages := make([]string, 0, len(errs))
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			assert.Equal(t, tt.expectedErrors, messages)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"log"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DuplicateThreshold is the lowest score at which a pair of applicants is
+// reported as a suspected duplicate.
+var DuplicateThreshold = 0.6
+
+// Weights of each signal in a duplicate score. Household overlap counts fully
+// from two members in common.
+const (
+	nameWeight        = 0.5
+	dateOfBirthWeight = 0.3
+	householdWeight   = 0.2
+)
+
+// ScoreDuplicate combines the signals that two applicants are the same person
+// into a score from 0 to 1.
+func ScoreDuplicate(nameSimilarity float64, sameDateOfBirth bool, householdOverlap int) float64 {
+	score := nameWeight * nameSimilarity
+	if sameDateOfBirth {
+		score += dateOfBirthWeight
+	}
+	if householdOverlap >= 2 {
+		score += householdWeight
+	} else if householdOverlap == 1 {
+		score += householdWeight / 2
+	}
+	return score
+}
+
+// candidatePairsQuery finds pairs of applicants with similar names or the same
+// date of birth, and counts the household members they have in common by
+// national ID or by name and date of birth.
+const candidatePairsQuery = `
+SELECT
+    a.id AS applicant_id,
+    b.id AS duplicate_id,
+    similarity(a.name, b.name) AS name_similarity,
+    a.date_of_birth = b.date_of_birth AS same_date_of_birth,
+    (
+        SELECT COUNT(*)
+        FROM household_members ma
+        JOIN household_members mb ON mb.applicant_id = b.id
+        WHERE ma.applicant_id = a.id
+          AND (ma.national_id = mb.national_id
+               OR (lower(ma.name) = lower(mb.name) AND ma.date_of_birth = mb.date_of_birth))
+    ) AS household_overlap
+FROM applicants a
+JOIN applicants b ON a.id < b.id AND (a.name % b.name OR a.date_of_birth = b.date_of_birth)`
+
+// DetectDuplicateApplicants scores every candidate pair of applicants and
+// replaces the stored suspected duplicates with those scoring at least
+// DuplicateThreshold. It returns the number of suspected duplicates.
+func DetectDuplicateApplicants(tx *gorm.DB) (int, error) {
+	var pairs []struct {
+		ApplicantID      uuid.UUID
+		DuplicateID      uuid.UUID
+		NameSimilarity   float64
+		SameDateOfBirth  bool
+		HouseholdOverlap int
+	}
+	if err := tx.Raw(candidatePairsQuery).Scan(&pairs).Error; err != nil {
+		return 0, err
+	}
+
+	candidates := make([]models.DuplicateCandidate, 0)
+	for _, pair := range pairs {
+		score := ScoreDuplicate(pair.NameSimilarity, pair.SameDateOfBirth, pair.HouseholdOverlap)
+		if score < DuplicateThreshold {
+			continue
+		}
+		candidates = append(candidates, models.DuplicateCandidate{
+			ApplicantID:      pair.ApplicantID,
+			DuplicateID:      pair.DuplicateID,
+			Score:            score,
+			NameSimilarity:   pair.NameSimilarity,
+			SameDateOfBirth:  pair.SameDateOfBirth,
+			HouseholdOverlap: pair.HouseholdOverlap,
+		})
+	}
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.DuplicateCandidate{}).Error; err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&candidates, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(candidates), nil
+}
+
+// RunDuplicateDetection runs DetectDuplicateApplicants now and then at every
+// interval. It is meant to be started in its own goroutine.
+func RunDuplicateDetection(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := DetectDuplicateApplicants(db.DB)
+		if err != nil {
+			log.Printf("duplicate detection failed: %v", err)
+		} else {
+			log.Printf("duplicate detection found %d suspected duplicate applicants", count)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreDuplicate(t *testing.T) {
+	tests := []struct {
+		name             string
+		nameSimilarity   float64
+		sameDateOfBirth  bool
+		householdOverlap int
+		expected         float64
+	}{
+		{name: "Identical", nameSimilarity: 1, sameDateOfBirth: true, householdOverlap: 3, expected: 1},
+		{name: "Same name only", nameSimilarity: 1, expected: 0.5},
+		{name: "Similar name and date of birth", nameSimilarity: 0.6, sameDateOfBirth: true, expected: 0.6},
+		{name: "One household member in common", nameSimilarity: 0.4, householdOverlap: 1, expected: 0.3},
+		{name: "Date of birth only", sameDateOfBirth: true, expected: 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, ScoreDuplicate(tt.nameSimilarity, tt.sameDateOfBirth, tt.householdOverlap), 1e-9)
+		})
+	}
+}
@@ -1,96 +1,500 @@
 package utils
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/bensiauu/financial-assistance-scheme/models"
 	"github.com/bensiauu/financial-assistance-scheme/pkg/db"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-func GetEligibleSchemes(applicantID string) ([]models.Scheme, error) {
+// SchemeEligibility is the outcome of evaluating one scheme for an applicant.
+// A scheme whose criteria or benefits could not be evaluated has Errors set and
+// is never eligible.
+type SchemeEligibility struct {
+	SchemeID   uuid.UUID       `json:"scheme_id"`
+	SchemeName string          `json:"scheme_name"`
+	Eligible   bool            `json:"eligible"`
+	Criteria   CriteriaResult  `json:"criteria"`
+	Errors     []CriteriaError `json:"errors,omitempty"`
+
+	// Entitlement is the benefit the applicant would receive, set when eligible.
+	Entitlement *models.Entitlement `json:"entitlement,omitempty"`
+}
+
+// CriteriaResult mirrors models.Criteria with the outcome of every rule and group.
+type CriteriaResult struct {
+	Passed bool             `json:"passed"`
+	Rules  []RuleResult     `json:"rules"`
+	All    []CriteriaResult `json:"all,omitempty"`
+	Any    []CriteriaResult `json:"any,omitempty"`
+	Not    *CriteriaResult  `json:"not,omitempty"`
+}
+
+// RuleResult records the value a rule was checked against and whether it passed.
+// For household rules Actual is the number of matching members and Members holds
+// the trace for each member. Error is set when the rule could not be evaluated.
+type RuleResult struct {
+	Field      string         `json:"field"`
+	Operator   string         `json:"operator"`
+	Quantifier string         `json:"quantifier,omitempty"`
+	Expected   interface{}    `json:"expected"`
+	Actual     interface{}    `json:"actual"`
+	Passed     bool           `json:"passed"`
+	Error      string         `json:"error,omitempty"`
+	Members    []MemberResult `json:"members,omitempty"`
+}
+
+// MemberResult is the outcome of a household rule's member predicate for one member.
+type MemberResult struct {
+	MemberID uuid.UUID      `json:"member_id"`
+	Name     string         `json:"name"`
+	Criteria CriteriaResult `json:"criteria"`
+}
+
+// EligibleScheme is a scheme the applicant qualifies for, together with the
+// benefits they would receive from it.
+type EligibleScheme struct {
+	models.Scheme
+	Entitlement models.Entitlement `json:"entitlement"`
+}
+
+// SkippedScheme is a scheme left out of an eligibility check because its
+// criteria are misconfigured.
+type SkippedScheme struct {
+	SchemeID   uuid.UUID       `json:"scheme_id"`
+	SchemeName string          `json:"scheme_name"`
+	Errors     []CriteriaError `json:"errors"`
+}
+
+// GetEligibleSchemes returns the schemes the applicant is eligible for as of
+// the given date and their entitlement under each, along with any schemes that
+// were skipped because their criteria or benefits could not be evaluated.
+func GetEligibleSchemes(applicantID string, asOf time.Time) ([]EligibleScheme, []SkippedScheme, error) {
 	var applicant models.Applicant
-	if err := db.DB.First(&applicant, "id = ?", applicantID).Error; err != nil {
-		return nil, err
+	if err := db.DB.Preload("Household").First(&applicant, "id = ?", applicantID).Error; err != nil {
+		return nil, nil, err
 	}
 
 	var schemes []models.Scheme
 	if err := db.DB.Find(&schemes).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var eligibleSchemes []EligibleScheme
+	var skippedSchemes []SkippedScheme
+	for _, scheme := range schemes {
+		result := EvaluateScheme(applicant, scheme, asOf)
+		if len(result.Errors) > 0 {
+			skippedSchemes = append(skippedSchemes, SkippedScheme{
+				SchemeID:   scheme.ID,
+				SchemeName: scheme.Name,
+				Errors:     result.Errors,
+			})
+			continue
+		}
+		if result.Eligible {
+			eligibleSchemes = append(eligibleSchemes, EligibleScheme{
+				Scheme:      scheme,
+				Entitlement: *result.Entitlement,
+			})
+		}
+	}
+
+	return eligibleSchemes, skippedSchemes, nil
+}
+
+// ExplainEligibility evaluates the applicant against a single scheme, or every
+// scheme when schemeID is empty, as of the given date and returns the full
+// evaluation trace.
+func ExplainEligibility(applicantID, schemeID string, asOf time.Time) ([]SchemeEligibility, error) {
+	var applicant models.Applicant
+	if err := db.DB.Preload("Household").First(&applicant, "id = ?", applicantID).Error; err != nil {
 		return nil, err
 	}
 
-	var eligibleSchemes []models.Scheme
+	var schemes []models.Scheme
+	if schemeID != "" {
+		var scheme models.Scheme
+		if err := db.DB.First(&scheme, "id = ?", schemeID).Error; err != nil {
+			return nil, err
+		}
+		schemes = append(schemes, scheme)
+	} else if err := db.DB.Find(&schemes).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]SchemeEligibility, 0, len(schemes))
 	for _, scheme := range schemes {
-		if isApplicantEligible(applicant, scheme.Criteria) {
-			eligibleSchemes = append(eligibleSchemes, scheme)
+		results = append(results, EvaluateScheme(applicant, scheme, asOf))
+	}
+
+	return results, nil
+}
+
+// EvaluateApplication re-evaluates an application against the criteria and
+// benefits of the scheme version it was created under, using the applicant's
+// current details. A version whose benefits cannot be decoded is reported in
+// Errors, whether or not the applicant meets its criteria.
+func EvaluateApplication(tx *gorm.DB, application models.Application, asOf time.Time) (SchemeEligibility, error) {
+	var applicant models.Applicant
+	if err := tx.Preload("Household").First(&applicant, "id = ?", application.ApplicantID).Error; err != nil {
+		return SchemeEligibility{}, err
+	}
+
+	var scheme models.Scheme
+	if err := tx.First(&scheme, "id = ?", application.SchemeID).Error; err != nil {
+		return SchemeEligibility{}, err
+	}
+
+	var version models.SchemeVersion
+	if err := tx.First(&version, "scheme_id = ? AND version = ?", application.SchemeID, application.SchemeVersion).Error; err != nil {
+		return SchemeEligibility{}, err
+	}
+
+	scheme.Version = version.Version
+	scheme.Criteria = version.Criteria
+	scheme.Benefits = version.Benefits
+	eligibility := EvaluateScheme(applicant, scheme, asOf)
+	if err := version.Benefits.Err(); err != nil && len(eligibility.Errors) == 0 {
+		eligibility.Errors = []CriteriaError{benefitsError(err)}
+	}
+	return eligibility, nil
+}
+
+// FlagIneligibleApplications re-checks the applicant's open applications and
+// flags those they no longer qualify for, clearing the flag on those they
+// qualify for again. It returns the IDs of the flagged applications.
+// Applications whose criteria or benefits cannot be evaluated are left
+// unchanged.
+func FlagIneligibleApplications(tx *gorm.DB, applicantID uuid.UUID, asOf time.Time) ([]uuid.UUID, error) {
+	var applications []models.Application
+	if err := tx.Where("applicant_id = ? AND status IN ?", applicantID, models.OpenApplicationStatuses).Find(&applications).Error; err != nil {
+		return nil, err
+	}
+
+	flagged := make([]uuid.UUID, 0)
+	for _, application := range applications {
+		result, err := EvaluateApplication(tx, application, asOf)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Errors) > 0 {
+			continue
+		}
+
+		if result.Eligible {
+			if application.IneligibleSince != nil {
+				if err := tx.Model(&application).Update("ineligible_since", nil).Error; err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		flagged = append(flagged, application.ID)
+		if application.IneligibleSince == nil {
+			if err := tx.Model(&application).Update("ineligible_since", asOf).Error; err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	return eligibleSchemes, nil
+	return flagged, nil
+}
+
+// EvaluateScheme evaluates a scheme's criteria for an applicant whose household
+// has already been loaded. Ages and elapsed durations are computed as of asOf.
+// An applicant who meets the criteria of a scheme whose benefits cannot be
+// decoded is not eligible, and the problem is reported at path "benefits".
+func EvaluateScheme(applicant models.Applicant, scheme models.Scheme, asOf time.Time) SchemeEligibility {
+	eligibility := SchemeEligibility{
+		SchemeID:   scheme.ID,
+		SchemeName: scheme.Name,
+	}
+
+	if err := scheme.Criteria.Err(); err != nil {
+		eligibility.Criteria = CriteriaResult{Rules: []RuleResult{}}
+		eligibility.Errors = []CriteriaError{{Message: fmt.Sprintf("invalid criteria JSON: %v", err)}}
+		return eligibility
+	}
+
+	result, errs := evaluateApplicant(applicant, scheme.Criteria, asOf)
+	eligibility.Criteria = result
+	eligibility.Errors = errs
+	eligibility.Eligible = result.Passed && len(errs) == 0
+	if !eligibility.Eligible {
+		return eligibility
+	}
+
+	if err := scheme.Benefits.Err(); err != nil {
+		eligibility.Eligible = false
+		eligibility.Errors = append(eligibility.Errors, benefitsError(err))
+		return eligibility
+	}
+	entitlement := CalculateEntitlement(scheme.Benefits, applicant, asOf)
+	eligibility.Entitlement = &entitlement
+	return eligibility
 }
 
-func isApplicantEligible(applicant models.Applicant, criteria models.Criteria) bool {
-	for _, rule := range criteria.Rules {
-		if !evaluateRule(applicant, rule) {
-			return false
+func benefitsError(err error) CriteriaError {
+	return CriteriaError{Path: "benefits", Message: fmt.Sprintf("invalid benefits JSON: %v", err)}
+}
+
+func isApplicantEligible(applicant models.Applicant, criteria models.Criteria, asOf time.Time) bool {
+	result, errs := evaluateApplicant(applicant, criteria, asOf)
+	return result.Passed && len(errs) == 0
+}
+
+func evaluateApplicant(applicant models.Applicant, criteria models.Criteria, asOf time.Time) (CriteriaResult, []CriteriaError) {
+	return evaluateCriteria(criteria, func(rule models.Rule) (RuleResult, []CriteriaError) {
+		return evaluateRule(applicant, rule, asOf)
+	})
+}
+
+// ruleEvaluator decides a single leaf rule. Error paths are relative to the rule.
+type ruleEvaluator func(models.Rule) (RuleResult, []CriteriaError)
+
+// evaluateCriteria walks a criteria group recursively, using evaluate to
+// decide each leaf rule. Every rule is evaluated so that the result is a
+// complete trace of the criteria, and errors are addressed relative to the group.
+func evaluateCriteria(criteria models.Criteria, evaluate ruleEvaluator) (CriteriaResult, []CriteriaError) {
+	result := CriteriaResult{Passed: true, Rules: make([]RuleResult, 0, len(criteria.Rules))}
+	var errs []CriteriaError
+
+	for i, rule := range criteria.Rules {
+		ruleResult, ruleErrs := evaluate(rule)
+		if !ruleResult.Passed {
+			result.Passed = false
+		}
+		result.Rules = append(result.Rules, ruleResult)
+		errs = append(errs, prefixErrors(fmt.Sprintf("rules[%d]", i), ruleErrs)...)
+	}
+
+	for i, group := range criteria.All {
+		groupResult, groupErrs := evaluateCriteria(group, evaluate)
+		if !groupResult.Passed {
+			result.Passed = false
+		}
+		result.All = append(result.All, groupResult)
+		errs = append(errs, prefixErrors(fmt.Sprintf("all[%d]", i), groupErrs)...)
+	}
+
+	if len(criteria.Any) > 0 {
+		anyPassed := false
+		for i, group := range criteria.Any {
+			groupResult, groupErrs := evaluateCriteria(group, evaluate)
+			if groupResult.Passed {
+				anyPassed = true
+			}
+			result.Any = append(result.Any, groupResult)
+			errs = append(errs, prefixErrors(fmt.Sprintf("any[%d]", i), groupErrs)...)
+		}
+		if !anyPassed {
+			result.Passed = false
 		}
 	}
-	return true
+
+	if criteria.Not != nil {
+		notResult, notErrs := evaluateCriteria(*criteria.Not, evaluate)
+		if notResult.Passed {
+			result.Passed = false
+		}
+		result.Not = &notResult
+		errs = append(errs, prefixErrors("not", notErrs)...)
+	}
+
+	return result, errs
+}
+
+func prefixErrors(prefix string, errs []CriteriaError) []CriteriaError {
+	prefixed := make([]CriteriaError, 0, len(errs))
+	for _, err := range errs {
+		prefixed = append(prefixed, CriteriaError{Path: joinPath(prefix, err.Path), Message: err.Message})
+	}
+	return prefixed
+}
+
+func newRuleResult(rule models.Rule) RuleResult {
+	return RuleResult{
+		Field:      rule.Field,
+		Operator:   rule.Operator,
+		Quantifier: rule.Quantifier,
+		Expected:   rule.Value,
+	}
+}
+
+// ruleOutcome finishes a leaf rule, failing it if it could not be evaluated.
+func ruleOutcome(result RuleResult, err *CriteriaError) (RuleResult, []CriteriaError) {
+	if err == nil {
+		return result, nil
+	}
+	result.Passed = false
+	result.Error = err.Message
+	return result, []CriteriaError{*err}
+}
+
+func unknownField(field string) *CriteriaError {
+	return &CriteriaError{Path: "field", Message: fmt.Sprintf("unknown field %q", field)}
 }
 
-func evaluateRule(applicant models.Applicant, rule models.Rule) bool {
+func evaluateRule(applicant models.Applicant, rule models.Rule, asOf time.Time) (RuleResult, []CriteriaError) {
+	result := newRuleResult(rule)
+	var err *CriteriaError
+
 	switch rule.Field {
 	case "income":
-		return compareInts(applicant.Income, rule.Operator, int(rule.Value.(float64)))
+		income := applicant.MonthlyIncome()
+		result.Actual = income
+		result.Passed, err = compareMoneyRule(income, rule)
 	case "employment_status":
-		return compareStrings(applicant.EmploymentStatus, rule.Operator, rule.Value.(string))
+		result.Actual = applicant.EmploymentStatus
+		result.Passed, err = compareStringRule(applicant.EmploymentStatus, rule)
 	case "age":
-		applicantAge := calculateAge(applicant.DateOfBirth)
-		return compareInts(applicantAge, rule.Operator, int(rule.Value.(float64)))
+		applicantAge := calculateAge(applicant.DateOfBirth, asOf)
+		result.Actual = applicantAge
+		result.Passed, err = compareNumberRule(applicantAge, rule)
 	case "marital_status":
-		return compareStrings(applicant.MaritalStatus, rule.Operator, rule.Value.(string))
+		result.Actual = applicant.MaritalStatus
+		result.Passed, err = compareStringRule(applicant.MaritalStatus, rule)
 	case "disability_status":
-		return compareStrings(applicant.DisabilityStatus, rule.Operator, rule.Value.(string))
+		result.Actual = applicant.DisabilityStatus
+		result.Passed, err = compareStringRule(applicant.DisabilityStatus, rule)
 	case "number_of_children":
-		return compareInts(applicant.NumberOfChildren, rule.Operator, int(rule.Value.(float64)))
-	// Add more fields as needed
+		result.Actual = applicant.NumberOfChildren
+		result.Passed, err = compareNumberRule(applicant.NumberOfChildren, rule)
+	case "date_of_birth":
+		result.Actual, result.Passed, err = compareDateField(&applicant.DateOfBirth, rule, asOf)
+	case "last_employed":
+		result.Actual, result.Passed, err = compareDateField(applicant.LastEmployed, rule, asOf)
+	case "household_size":
+		// The applicant counts towards the size of their own household.
+		householdSize := len(applicant.Household) + 1
+		result.Actual = householdSize
+		result.Passed, err = compareNumberRule(householdSize, rule)
+	case "household_income":
+		householdIncome := applicant.HouseholdIncome()
+		result.Actual = householdIncome
+		result.Passed, err = compareMoneyRule(householdIncome, rule)
+	case "per_capita_income":
+		perCapitaIncome := applicant.PerCapitaIncome()
+		result.Actual = perCapitaIncome
+		result.Passed, err = compareMoneyRule(perCapitaIncome, rule)
+	case "household":
+		return evaluateHouseholdRule(applicant.Household, rule, result, asOf)
 	default:
-		return false
+		err = unknownField(rule.Field)
 	}
+
+	return ruleOutcome(result, err)
 }
 
-func calculateAge(dob time.Time) int {
-	today := time.Now()
-	age := today.Year() - dob.Year()
-	if today.YearDay() < dob.YearDay() {
-		age--
+// evaluateHouseholdRule applies the rule's quantifier to the household members
+// matching rule.Member. A rule without a member predicate matches every member.
+// A misconfigured member predicate fails the rule even for an empty household.
+func evaluateHouseholdRule(household []models.HouseholdMember, rule models.Rule, result RuleResult, asOf time.Time) (RuleResult, []CriteriaError) {
+	if rule.Member != nil {
+		if errs := validateCriteria(*rule.Member, "member", memberFields, nil); len(errs) > 0 {
+			result.Passed = false
+			result.Error = "member predicate is invalid"
+			return result, errs
+		}
 	}
-	return age
-}
 
-func compareInts(a int, operator string, b int) bool {
-	switch operator {
-	case "==":
-		return a == b
-	case ">=":
-		return a >= b
-	case "<=":
-		return a <= b
-	case ">":
-		return a > b
-	case "<":
-		return a < b
+	matches := 0
+	var errs []CriteriaError
+	seen := make(map[string]bool)
+	for _, member := range household {
+		if rule.Member == nil {
+			matches++
+			continue
+		}
+
+		memberResult, memberErrs := evaluateMember(member, *rule.Member, asOf)
+		if memberResult.Passed {
+			matches++
+		}
+		result.Members = append(result.Members, MemberResult{
+			MemberID: member.ID,
+			Name:     member.Name,
+			Criteria: memberResult,
+		})
+
+		// Every member reports the same misconfigured rules, so keep one of each.
+		for _, err := range prefixErrors("member", memberErrs) {
+			if !seen[err.Error()] {
+				seen[err.Error()] = true
+				errs = append(errs, err)
+			}
+		}
+	}
+	result.Actual = matches
+
+	var err *CriteriaError
+	switch rule.Quantifier {
+	case "any":
+		result.Passed = matches > 0
+	case "all":
+		result.Passed = matches == len(household)
+	case "count":
+		result.Passed, err = compareNumberRule(matches, rule)
 	default:
-		return false
+		err = &CriteriaError{Path: "quantifier", Message: fmt.Sprintf("unsupported quantifier %q", rule.Quantifier)}
 	}
+
+	result, quantifierErrs := ruleOutcome(result, err)
+	if len(errs) > 0 {
+		result.Passed = false
+		if result.Error == "" {
+			result.Error = "member predicate could not be evaluated"
+		}
+	}
+	return result, append(quantifierErrs, errs...)
+}
+
+func evaluateMember(member models.HouseholdMember, criteria models.Criteria, asOf time.Time) (CriteriaResult, []CriteriaError) {
+	return evaluateCriteria(criteria, func(rule models.Rule) (RuleResult, []CriteriaError) {
+		return evaluateMemberRule(member, rule, asOf)
+	})
 }
 
-func compareStrings(a string, operator string, b string) bool {
-	switch operator {
-	case "==":
-		return a == b
-	case "!=":
-		return a != b
+func evaluateMemberRule(member models.HouseholdMember, rule models.Rule, asOf time.Time) (RuleResult, []CriteriaError) {
+	result := newRuleResult(rule)
+	var err *CriteriaError
+
+	switch rule.Field {
+	case "relation":
+		result.Actual = member.Relation
+		result.Passed, err = compareStringRule(member.Relation, rule)
+	case "age":
+		memberAge := calculateAge(member.DateOfBirth, asOf)
+		result.Actual = memberAge
+		result.Passed, err = compareNumberRule(memberAge, rule)
+	case "date_of_birth":
+		result.Actual, result.Passed, err = compareDateField(&member.DateOfBirth, rule, asOf)
+	case "employment_status":
+		result.Actual = member.EmploymentStatus
+		result.Passed, err = compareStringRule(member.EmploymentStatus, rule)
+	case "income":
+		income := member.MonthlyIncome()
+		result.Actual = income
+		result.Passed, err = compareMoneyRule(income, rule)
 	default:
-		return false
+		err = unknownField(rule.Field)
 	}
+
+	return ruleOutcome(result, err)
+}
+
+// calculateAge returns the age in whole years on asOf. A birthday counts once
+// its month and day have been reached, so someone born on 29 February turns a
+// year older on 1 March in non-leap years.
+func calculateAge(dob time.Time, asOf time.Time) int {
+	age := asOf.Year() - dob.Year()
+	if asOf.Month() < dob.Month() || (asOf.Month() == dob.Month() && asOf.Day() < dob.Day()) {
+		age--
+	}
+	return age
 }
@@ -0,0 +1,457 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsApplicantEligibleWithGroups(t *testing.T) {
+	applicant := models.Applicant{
+		Name:             "John Doe",
+		EmploymentStatus: "unemployed",
+		DateOfBirth:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		Income:           models.NewMoney(1500),
+		MaritalStatus:    "widowed",
+		DisabilityStatus: "none",
+	}
+
+	tests := []struct {
+		name     string
+		criteria string
+		expected bool
+	}{
+		{
+			name:     "Flat rules are ANDed",
+			criteria: `{"rules": [{"field": "income", "operator": "<", "value": 2000}, {"field": "employment_status", "operator": "==", "value": "unemployed"}]}`,
+			expected: true,
+		},
+		{
+			name:     "Flat rules fail when one rule fails",
+			criteria: `{"rules": [{"field": "income", "operator": "<", "value": 2000}, {"field": "employment_status", "operator": "==", "value": "employed"}]}`,
+			expected: false,
+		},
+		{
+			name:     "Money is compared exactly",
+			criteria: `{"rules": [{"field": "income", "operator": "between", "value": [{"amount": 1500, "currency": "SGD"}, 1501]}, {"field": "income", "operator": "<", "value": 1500}]}`,
+			expected: false,
+		},
+		{
+			name:     "Empty criteria",
+			criteria: `{"rules": []}`,
+			expected: true,
+		},
+		{
+			name: "Any group passes when one branch passes",
+			criteria: `{"any": [
+				{"rules": [{"field": "marital_status", "operator": "==", "value": "single"}]},
+				{"rules": [{"field": "marital_status", "operator": "==", "value": "widowed"}]}
+			]}`,
+			expected: true,
+		},
+		{
+			name: "Any group fails when no branch passes",
+			criteria: `{"any": [
+				{"rules": [{"field": "marital_status", "operator": "==", "value": "single"}]},
+				{"rules": [{"field": "marital_status", "operator": "==", "value": "divorced"}]}
+			]}`,
+			expected: false,
+		},
+		{
+			name: "Rules combined with nested any",
+			criteria: `{"rules": [{"field": "income", "operator": "<", "value": 2000}], "any": [
+				{"rules": [{"field": "disability_status", "operator": "==", "value": "disabled"}]},
+				{"rules": [{"field": "age", "operator": ">=", "value": 65}]}
+			]}`,
+			expected: false,
+		},
+		{
+			name: "All group",
+			criteria: `{"all": [
+				{"rules": [{"field": "income", "operator": "<", "value": 2000}]},
+				{"any": [{"rules": [{"field": "marital_status", "operator": "==", "value": "widowed"}]}]}
+			]}`,
+			expected: true,
+		},
+		{
+			name:     "Not group negates",
+			criteria: `{"not": {"rules": [{"field": "employment_status", "operator": "==", "value": "employed"}]}}`,
+			expected: true,
+		},
+		{
+			name:     "Not group fails when inner group passes",
+			criteria: `{"not": {"rules": [{"field": "employment_status", "operator": "==", "value": "unemployed"}]}}`,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var criteria models.Criteria
+			err := json.Unmarshal([]byte(tt.criteria), &criteria)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, isApplicantEligible(applicant, criteria, time.Now()))
+		})
+	}
+}
+
+func TestIsApplicantEligibleWithHousehold(t *testing.T) {
+	now := time.Now()
+	applicant := models.Applicant{
+		Name:             "Jane Doe",
+		EmploymentStatus: "employed",
+		DateOfBirth:      time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC),
+		Income:           models.NewMoney(300000),
+		IncomePeriod:     models.IncomeMonthly,
+		Household: []models.HouseholdMember{
+			{Name: "John Doe", Relation: "spouse", DateOfBirth: time.Date(1984, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "unemployed",
+				Income: models.NewMoney(1200000), IncomePeriod: models.IncomeAnnual},
+			{Name: "Jimmy Doe", Relation: "son", DateOfBirth: now.AddDate(-6, 0, 0), EmploymentStatus: "primary_school"},
+			{Name: "Jenny Doe", Relation: "daughter", DateOfBirth: now.AddDate(-10, 0, 0), EmploymentStatus: "primary_school"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		criteria string
+		expected bool
+	}{
+		{
+			name:     "Household size includes the applicant",
+			criteria: `{"rules": [{"field": "household_size", "operator": ">=", "value": 4}]}`,
+			expected: true,
+		},
+		{
+			name:     "Household size too small",
+			criteria: `{"rules": [{"field": "household_size", "operator": ">=", "value": 5}]}`,
+			expected: false,
+		},
+		{
+			name: "Any member unemployed",
+			criteria: `{"rules": [{"field": "household", "quantifier": "any", "member": {"rules": [
+				{"field": "employment_status", "operator": "==", "value": "unemployed"}
+			]}}]}`,
+			expected: true,
+		},
+		{
+			name: "Child under 7 in primary school",
+			criteria: `{"rules": [{"field": "household", "quantifier": "any", "member": {"rules": [
+				{"field": "age", "operator": "<", "value": 7},
+				{"field": "employment_status", "operator": "==", "value": "primary_school"}
+			]}}]}`,
+			expected: true,
+		},
+		{
+			name: "All members in primary school",
+			criteria: `{"rules": [{"field": "household", "quantifier": "all", "member": {"rules": [
+				{"field": "employment_status", "operator": "==", "value": "primary_school"}
+			]}}]}`,
+			expected: false,
+		},
+		{
+			name: "Count of children",
+			criteria: `{"rules": [{"field": "household", "quantifier": "count", "operator": "==", "value": 2, "member": {"any": [
+				{"rules": [{"field": "relation", "operator": "==", "value": "son"}]},
+				{"rules": [{"field": "relation", "operator": "==", "value": "daughter"}]}
+			]}}]}`,
+			expected: true,
+		},
+		{
+			name:     "Household income is monthly",
+			criteria: `{"rules": [{"field": "household_income", "operator": "==", "value": 400000}]}`,
+			expected: true,
+		},
+		{
+			name:     "Per capita income",
+			criteria: `{"rules": [{"field": "per_capita_income", "operator": "<", "value": 100000}]}`,
+			expected: false,
+		},
+		{
+			name: "Member with an income",
+			criteria: `{"rules": [{"field": "household", "quantifier": "count", "operator": "==", "value": 1, "member": {"rules": [
+				{"field": "income", "operator": ">", "value": 0}
+			]}}]}`,
+			expected: true,
+		},
+		{
+			name:     "Unknown quantifier",
+			criteria: `{"rules": [{"field": "household", "quantifier": "most"}]}`,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var criteria models.Criteria
+			err := json.Unmarshal([]byte(tt.criteria), &criteria)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, isApplicantEligible(applicant, criteria, time.Now()))
+		})
+	}
+}
+
+func TestEvaluateSchemeTrace(t *testing.T) {
+	applicant := models.Applicant{
+		Name:             "John Doe",
+		EmploymentStatus: "employed",
+		DateOfBirth:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		Income:           models.NewMoney(2500),
+		MaritalStatus:    "single",
+		Household: []models.HouseholdMember{
+			{Name: "Jane Doe", Relation: "mother", DateOfBirth: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "unemployed"},
+		},
+	}
+
+	var criteria models.Criteria
+	err := json.Unmarshal([]byte(`{
+		"rules": [
+			{"field": "income", "operator": "<", "value": 2000},
+			{"field": "household", "quantifier": "any", "member": {"rules": [{"field": "relation", "operator": "==", "value": "mother"}]}}
+		],
+		"any": [{"rules": [{"field": "marital_status", "operator": "==", "value": "single"}]}]
+	}`), &criteria)
+	assert.NoError(t, err)
+
+	result := EvaluateScheme(applicant, models.Scheme{Name: "Low Income Assistance", Criteria: criteria}, time.Now())
+
+	assert.False(t, result.Eligible)
+	assert.Equal(t, "Low Income Assistance", result.SchemeName)
+	assert.Len(t, result.Criteria.Rules, 2)
+
+	income := result.Criteria.Rules[0]
+	assert.Equal(t, "income", income.Field)
+	assert.Equal(t, "<", income.Operator)
+	assert.Equal(t, models.NewMoney(2500), income.Actual)
+	assert.Equal(t, float64(2000), income.Expected)
+	assert.False(t, income.Passed)
+
+	household := result.Criteria.Rules[1]
+	assert.True(t, household.Passed)
+	assert.Equal(t, 1, household.Actual)
+	assert.Len(t, household.Members, 1)
+	assert.Equal(t, "Jane Doe", household.Members[0].Name)
+	assert.True(t, household.Members[0].Criteria.Passed)
+
+	assert.Len(t, result.Criteria.Any, 1)
+	assert.True(t, result.Criteria.Any[0].Passed)
+}
+
+func TestEvaluateSchemeWithMalformedBenefits(t *testing.T) {
+	applicant := models.Applicant{Name: "John Doe", Income: models.NewMoney(1500)}
+
+	var criteria models.Criteria
+	err := json.Unmarshal([]byte(`{"rules": [{"field": "income", "operator": "<", "value": 2000}]}`), &criteria)
+	assert.NoError(t, err)
+
+	// Schemes created before benefits were structured may hold any JSON.
+	var benefits models.Benefits
+	assert.NoError(t, benefits.Scan([]byte(`{"amount": "lots"}`)))
+	assert.Error(t, benefits.Err())
+
+	result := EvaluateScheme(applicant, models.Scheme{Criteria: criteria, Benefits: benefits}, time.Now())
+	assert.False(t, result.Eligible)
+	assert.True(t, result.Criteria.Passed)
+	assert.Nil(t, result.Entitlement)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, "benefits", result.Errors[0].Path)
+	}
+
+	// Benefits are only calculated for applicants who meet the criteria.
+	applicant.Income = models.NewMoney(2500)
+	result = EvaluateScheme(applicant, models.Scheme{Criteria: criteria, Benefits: benefits}, time.Now())
+	assert.False(t, result.Eligible)
+	assert.Empty(t, result.Errors)
+}
+
+func TestEvaluateSchemeWithHostileCriteria(t *testing.T) {
+	applicant := models.Applicant{
+		Name:             "John Doe",
+		EmploymentStatus: "unemployed",
+		DateOfBirth:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		Income:           models.NewMoney(1500),
+		Household: []models.HouseholdMember{
+			{Name: "Jane Doe", Relation: "spouse", DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "employed"},
+			{Name: "Jimmy Doe", Relation: "son", DateOfBirth: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), EmploymentStatus: "primary_school"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		criteria       string
+		expectedErrors []string // Prefixes of the expected error messages
+	}{
+		{
+			name:           "String value for number field",
+			criteria:       `{"rules": [{"field": "income", "operator": "<=", "value": "2000"}]}`,
+			expectedErrors: []string{"rules[0].value: expected number"},
+		},
+		{
+			name:           "Number value for string field",
+			criteria:       `{"rules": [{"field": "employment_status", "operator": "==", "value": 0}]}`,
+			expectedErrors: []string{"rules[0].value: expected string"},
+		},
+		{
+			name:           "Null value",
+			criteria:       `{"rules": [{"field": "age", "operator": ">=", "value": null}]}`,
+			expectedErrors: []string{"rules[0].value: expected number"},
+		},
+		{
+			name:           "Array value",
+			criteria:       `{"rules": [{"field": "marital_status", "operator": "==", "value": ["single"]}]}`,
+			expectedErrors: []string{"rules[0].value: expected string"},
+		},
+		{
+			name:           "Object value",
+			criteria:       `{"rules": [{"field": "age", "operator": "<", "value": {"amount": 1}}]}`,
+			expectedErrors: []string{"rules[0].value: expected number"},
+		},
+		{
+			name:           "Fractional value",
+			criteria:       `{"rules": [{"field": "number_of_children", "operator": ">=", "value": 1.5}]}`,
+			expectedErrors: []string{"rules[0].value: expected whole number"},
+		},
+		{
+			name:           "Unsupported operator",
+			criteria:       `{"rules": [{"field": "income", "operator": "=~", "value": 2000}]}`,
+			expectedErrors: []string{`rules[0].operator: unsupported operator "=~"`},
+		},
+		{
+			name:           "Unknown field",
+			criteria:       `{"rules": [{"field": "housing_status", "operator": "==", "value": "rented"}]}`,
+			expectedErrors: []string{`rules[0].field: unknown field "housing_status"`},
+		},
+		{
+			name:           "Error inside nested group",
+			criteria:       `{"any": [{"rules": [{"field": "income", "operator": "<", "value": 2000}]}, {"not": {"rules": [{"field": "age", "operator": ">", "value": true}]}}]}`,
+			expectedErrors: []string{"any[1].not.rules[0].value: expected number"},
+		},
+		{
+			name:           "Household rule without quantifier",
+			criteria:       `{"rules": [{"field": "household"}]}`,
+			expectedErrors: []string{`rules[0].quantifier: unsupported quantifier ""`},
+		},
+		{
+			name:           "Household count with string value",
+			criteria:       `{"rules": [{"field": "household", "quantifier": "count", "operator": ">=", "value": "two"}]}`,
+			expectedErrors: []string{"rules[0].value: expected number"},
+		},
+		{
+			name:           "Household member predicate with bad value",
+			criteria:       `{"rules": [{"field": "household", "quantifier": "any", "member": {"rules": [{"field": "relation", "operator": "==", "value": 7}]}}]}`,
+			expectedErrors: []string{"rules[0].member.rules[0].value: expected string"},
+		},
+		{
+			name:           "Rules is not an array",
+			criteria:       `{"rules": {"field": "income", "operator": "<", "value": 2000}}`,
+			expectedErrors: []string{"invalid criteria JSON: json: cannot unmarshal object"},
+		},
+		{
+			name:           "Criteria is not an object",
+			criteria:       `"income < 2000"`,
+			expectedErrors: []string{"invalid criteria JSON: json: cannot unmarshal string"},
+		},
+		{
+			name:           "Valid criteria",
+			criteria:       `{"rules": [{"field": "income", "operator": "<", "value": 2000}]}`,
+			expectedErrors: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var criteria models.Criteria
+			err := criteria.Scan([]byte(tt.criteria))
+			assert.NoError(t, err)
+
+			var result SchemeEligibility
+			assert.NotPanics(t, func() {
+				result = EvaluateScheme(applicant, models.Scheme{Name: "Hostile Scheme", Criteria: criteria}, time.Now())
+			})
+
+			assert.Len(t, result.Errors, len(tt.expectedErrors))
+			for i, expected := range tt.expectedErrors {
+				if i < len(result.Errors) {
+					assert.True(t, strings.HasPrefix(result.Errors[i].Error(), expected), "error %q should start with %q", result.Errors[i].Error(), expected)
+				}
+			}
+			assert.Equal(t, len(tt.expectedErrors) == 0, result.Eligible)
+		})
+	}
+}
+
+func TestEvaluateSchemeWithInvalidMemberPredicateAndEmptyHousehold(t *testing.T) {
+	applicant := models.Applicant{Name: "John Doe", DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name          string
+		criteria      string
+		expectedError string
+	}{
+		{
+			name:          "All members with unknown field",
+			criteria:      `{"rules": [{"field": "household", "quantifier": "all", "member": {"rules": [{"field": "housing_status", "operator": "==", "value": "rented"}]}}]}`,
+			expectedError: `rules[0].member.rules[0].field: unknown field "housing_status"`,
+		},
+		{
+			name:          "No members with unsupported operator",
+			criteria:      `{"rules": [{"field": "household", "quantifier": "count", "operator": "==", "value": 0, "member": {"rules": [{"field": "relation", "operator": "=~", "value": "son"}]}}]}`,
+			expectedError: `rules[0].member.rules[0].operator: unsupported operator "=~"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var criteria models.Criteria
+			err := criteria.Scan([]byte(tt.criteria))
+			assert.NoError(t, err)
+
+			result := EvaluateScheme(applicant, models.Scheme{Criteria: criteria}, time.Now())
+			assert.False(t, result.Eligible)
+			if assert.Len(t, result.Errors, 1) {
+				assert.True(t, strings.HasPrefix(result.Errors[0].Error(), tt.expectedError), "error %q should start with %q", result.Errors[0].Error(), tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestCalculateAge(t *testing.T) {
+	tests := []struct {
+		name     string
+		dob      time.Time
+		asOf     time.Time
+		expected int
+	}{
+		{"Day before birthday", time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC), 33},
+		{"On birthday", time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), 34},
+		{"Birthday after leap day in leap year", time.Date(1990, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), 33},
+		{"Birthday reached in leap year", time.Date(1990, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 34},
+		{"Born in leap year before leap day", time.Date(2000, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC), 23},
+		{"Born on leap day in non-leap year", time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC), 22},
+		{"Born on leap day after 28 February", time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), 23},
+		{"Born on leap day in leap year", time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), 24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, calculateAge(tt.dob, tt.asOf))
+		})
+	}
+}
+
+func TestEvaluateSchemeAsOf(t *testing.T) {
+	applicant := models.Applicant{
+		Name:        "John Doe",
+		DateOfBirth: time.Date(1959, 8, 1, 0, 0, 0, 0, time.UTC),
+	}
+	scheme := models.Scheme{
+		Name:     "Senior Assistance",
+		Criteria: models.Criteria{Rules: []models.Rule{{Field: "age", Operator: ">=", Value: float64(65)}}},
+	}
+
+	assert.False(t, EvaluateScheme(applicant, scheme, time.Date(2024, 7, 31, 0, 0, 0, 0, time.UTC)).Eligible)
+	assert.True(t, EvaluateScheme(applicant, scheme, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)).Eligible)
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsStatusHistoryViolation reports whether err was raised by the database to
+// protect application status history: by the foreign key that keeps an
+// application with history from being deleted, or by the trigger that makes
+// the history append-only.
+func IsStatusHistoryViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "23503":
+		return pgErr.ConstraintName == "application_status_changes_application_id_fkey"
+	case "P0001":
+		return pgErr.Message == "application_status_changes is append-only"
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsStatusHistoryViolation(t *testing.T) {
+	restrict := &pgconn.PgError{Code: "23503", ConstraintName: "application_status_changes_application_id_fkey"}
+	appendOnly := &pgconn.PgError{Code: "P0001", Message: "application_status_changes is append-only"}
+
+	assert.True(t, IsStatusHistoryViolation(restrict))
+	assert.True(t, IsStatusHistoryViolation(fmt.Errorf("delete: %w", appendOnly)))
+	assert.False(t, IsStatusHistoryViolation(&pgconn.PgError{Code: "23503", ConstraintName: "applications_applicant_id_fkey"}))
+	assert.False(t, IsStatusHistoryViolation(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, IsStatusHistoryViolation(errors.New("application_status_changes is append-only")))
+}
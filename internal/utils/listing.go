@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// FilterKind is the type of value a list filter accepts.
+type FilterKind int
+
+const (
+	StringFilter FilterKind = iota
+	UUIDFilter
+)
+
+// ListOptions describes what a list endpoint can be filtered and sorted by.
+// Filters and sort keys are named after the columns they apply to, so only
+// whitelisted columns ever reach the query.
+type ListOptions struct {
+	Filters     map[string]FilterKind
+	Sorts       []string
+	DefaultSort string
+}
+
+// ListQuery is a parsed page request: the page, exact-match filters keyed by
+// column, and the ORDER BY clause.
+type ListQuery struct {
+	Page     int
+	PageSize int
+	Filters  map[string]interface{}
+	Order    string
+}
+
+// Page is one page of a list endpoint's results with the total number of
+// matching rows.
+type Page struct {
+	Items    interface{} `json:"items"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Total    int64       `json:"total"`
+}
+
+// ParseListQuery reads page, page_size, sort and filter parameters from a
+// query string. sort takes a key, prefixed with "-" for descending order.
+func ParseListQuery(values url.Values, options ListOptions) (ListQuery, error) {
+	query := ListQuery{Page: 1, PageSize: defaultPageSize, Filters: map[string]interface{}{}}
+
+	if page := values.Get("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return ListQuery{}, fmt.Errorf("page must be a positive integer")
+		}
+		query.Page = n
+	}
+	if pageSize := values.Get("page_size"); pageSize != "" {
+		n, err := strconv.Atoi(pageSize)
+		if err != nil || n < 1 || n > maxPageSize {
+			return ListQuery{}, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+		query.PageSize = n
+	}
+
+	key := values.Get("sort")
+	if key == "" {
+		key = options.DefaultSort
+	}
+	direction := "ASC"
+	if strings.HasPrefix(key, "-") {
+		key, direction = key[1:], "DESC"
+	}
+	if !contains(options.Sorts, key) {
+		sorts := append([]string(nil), options.Sorts...)
+		sort.Strings(sorts)
+		return ListQuery{}, fmt.Errorf("unsupported sort key %q, expected one of %s", key, strings.Join(sorts, ", "))
+	}
+	// Break ties on the primary key so pages don't overlap.
+	query.Order = fmt.Sprintf("%s %s, id", key, direction)
+
+	for column, kind := range options.Filters {
+		value := values.Get(column)
+		if value == "" {
+			continue
+		}
+		if kind == UUIDFilter {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return ListQuery{}, fmt.Errorf("%s must be a UUID", column)
+			}
+			query.Filters[column] = id
+			continue
+		}
+		query.Filters[column] = value
+	}
+
+	return query, nil
+}
+
+// Find loads one page of rows matching the query's filters into dest, a
+// pointer to a slice of models, along with the given associations, and
+// returns the total number of matching rows.
+func (q ListQuery) Find(tx *gorm.DB, dest interface{}, preloads ...string) (Page, error) {
+	filtered := tx.Model(dest)
+	for column, value := range q.Filters {
+		filtered = filtered.Where(fmt.Sprintf("%s = ?", column), value)
+	}
+	filtered = filtered.Session(&gorm.Session{})
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
+		return Page{}, err
+	}
+
+	find := filtered.Order(q.Order).Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize)
+	for _, association := range preloads {
+		find = find.Preload(association)
+	}
+	if err := find.Find(dest).Error; err != nil {
+		return Page{}, err
+	}
+
+	return Page{Page: q.Page, PageSize: q.PageSize, Total: total}, nil
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListQuery(t *testing.T) {
+	options := ListOptions{
+		Filters:     map[string]FilterKind{"status": StringFilter, "scheme_id": UUIDFilter},
+		Sorts:       []string{"created_at", "status"},
+		DefaultSort: "-created_at",
+	}
+	schemeID := uuid.New()
+
+	tests := []struct {
+		name          string
+		query         string
+		expected      ListQuery
+		expectedError string
+	}{
+		{
+			name:     "Defaults",
+			query:    "",
+			expected: ListQuery{Page: 1, PageSize: 20, Filters: map[string]interface{}{}, Order: "created_at DESC, id"},
+		},
+		{
+			name:  "Page, sort and filters",
+			query: "page=3&page_size=50&sort=status&status=submitted&scheme_id=" + schemeID.String() + "&name=ignored",
+			expected: ListQuery{
+				Page:     3,
+				PageSize: 50,
+				Filters:  map[string]interface{}{"status": "submitted", "scheme_id": schemeID},
+				Order:    "status ASC, id",
+			},
+		},
+		{
+			name:          "Invalid page",
+			query:         "page=0",
+			expectedError: "page must be a positive integer",
+		},
+		{
+			name:          "Page size too large",
+			query:         "page_size=1000",
+			expectedError: "page_size must be between 1 and 100",
+		},
+		{
+			name:          "Unsupported sort key",
+			query:         "sort=-name",
+			expectedError: `unsupported sort key "name", expected one of created_at, status`,
+		},
+		{
+			name:          "Sort injection",
+			query:         "sort=created_at%3BDROP+TABLE+applicants",
+			expectedError: `unsupported sort key "created_at;DROP TABLE applicants", expected one of created_at, status`,
+		},
+		{
+			name:          "Invalid UUID filter",
+			query:         "scheme_id=abc",
+			expectedError: "scheme_id must be a UUID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+
+			query, err := ParseListQuery(values, options)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
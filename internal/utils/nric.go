@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// nricWeights are the weights applied to the seven digits of an NRIC/FIN.
+var nricWeights = [7]int{2, 7, 6, 5, 4, 3, 2}
+
+// nricChecksums maps each NRIC/FIN prefix to the offset added to the weighted
+// sum and the check letters indexed by the sum modulo 11.
+var nricChecksums = map[byte]struct {
+	offset  int
+	letters string
+}{
+	'S': {0, "JZIHGFEDCBA"}, // Citizens and PRs born before 2000
+	'T': {4, "JZIHGFEDCBA"}, // Citizens and PRs born from 2000
+	'F': {0, "XWUTRQPNMLK"}, // Foreigners issued a FIN before 2000
+	'G': {4, "XWUTRQPNMLK"}, // Foreigners issued a FIN from 2000 to 2021
+	'M': {3, "XWUTRQPNJLK"}, // Foreigners issued a FIN from 2022
+}
+
+// NormalizeNationalID trims and uppercases a national ID.
+func NormalizeNationalID(id string) string {
+	return strings.ToUpper(strings.TrimSpace(id))
+}
+
+// ValidateNationalID checks that a normalized national ID is a Singapore
+// NRIC or FIN: a prefix letter, seven digits and a matching check letter.
+func ValidateNationalID(id string) error {
+	if len(id) != 9 {
+		return fmt.Errorf("must be 9 characters, e.g. S1234567D")
+	}
+
+	checksum, ok := nricChecksums[id[0]]
+	if !ok {
+		return fmt.Errorf("must start with S, T, F, G or M")
+	}
+
+	sum := checksum.offset
+	for i, weight := range nricWeights {
+		digit := id[i+1]
+		if digit < '0' || digit > '9' {
+			return fmt.Errorf("must have seven digits after the prefix")
+		}
+		sum += int(digit-'0') * weight
+	}
+
+	if id[8] != checksum.letters[sum%11] {
+		return fmt.Errorf("has an invalid check letter")
+	}
+	return nil
+}
+
+// MaskNationalID hides the first four digits of a national ID, e.g.
+// S1234567D becomes S****567D.
+func MaskNationalID(id string) string {
+	if len(id) != 9 {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:1] + "****" + id[5:]
+}
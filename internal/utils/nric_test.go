@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNationalID(t *testing.T) {
+	tests := []struct {
+		id            string
+		expectedError string
+	}{
+		{id: "S1234567D"},
+		{id: "T1234567J"},
+		{id: "F1234567N"},
+		{id: "G1234567X"},
+		{id: "S0000001I"},
+		{id: "S1234567A", expectedError: "has an invalid check letter"},
+		{id: "F1234567D", expectedError: "has an invalid check letter"},
+		{id: "A1234567D", expectedError: "must start with S, T, F, G or M"},
+		{id: "S12345X7D", expectedError: "must have seven digits after the prefix"},
+		{id: "S123456D", expectedError: "must be 9 characters, e.g. S1234567D"},
+		{id: "", expectedError: "must be 9 characters, e.g. S1234567D"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := ValidateNationalID(tt.id)
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestNormalizeAndMaskNationalID(t *testing.T) {
+	assert.Equal(t, "S1234567D", NormalizeNationalID(" s1234567d "))
+	assert.Equal(t, "S****567D", MaskNationalID("S1234567D"))
+	assert.Equal(t, "****", MaskNationalID("S123"))
+}
@@ -0,0 +1,562 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+)
+
+// Operator semantics by field type. A "-" means the operator is not supported
+// for that type and is rejected by ValidateCriteria.
+//
+//	operator        number                   string                      date
+//	==, !=          equality                 equality                    -
+//	>, >=, <, <=    ordering                 -                           -
+//	in, not_in      membership of a list     membership of a list        -
+//	between         inclusive [min, max]     -                           inclusive [from, to]
+//	before, after   -                        -                           strictly before/after
+//	on              -                        -                           same calendar day
+//	regex           -                        RE2 match anywhere in value -
+//	exists          always set               set when non-empty          set when not null
+//
+// The value of "exists" is a boolean, defaulting to true when omitted, so
+// {"operator": "exists", "value": false} matches a missing value. Setting
+// ignore_case on a string rule makes ==, !=, in, not_in and regex case-insensitive.
+// Date values are written as "YYYY-MM-DD". A date rule with elapsed set is
+// compared using the number operators, and never passes when the date is null.
+// Money fields also use the number operators, on exact amounts written either
+// as a whole number of minor units in the default currency or as
+// {"amount": 150000, "currency": "SGD"}.
+var operatorsByType = map[fieldType][]string{
+	numberField: {"==", "!=", ">=", "<=", ">", "<", "in", "not_in", "between", "exists"},
+	moneyField:  {"==", "!=", ">=", "<=", ">", "<", "in", "not_in", "between", "exists"},
+	stringField: {"==", "!=", "in", "not_in", "regex", "exists"},
+	dateField:   {"before", "after", "on", "between", "exists"},
+}
+
+var elapsedUnits = []string{"days", "months", "years"}
+
+// Clock returns the current time, used as the evaluation date when no other
+// date is requested. Tests replace it to make evaluation reproducible.
+var Clock = time.Now
+
+const dateLayout = "2006-01-02"
+
+func checkOperator(fieldType fieldType, operator string) *CriteriaError {
+	operators := operatorsByType[fieldType]
+	if contains(operators, operator) {
+		return nil
+	}
+	return &CriteriaError{
+		Path:    "operator",
+		Message: fmt.Sprintf("unsupported operator %q, expected one of %s", operator, strings.Join(operators, ", ")),
+	}
+}
+
+// checkOperands reports whether the rule's value has the shape its operator
+// needs, without evaluating it.
+func checkOperands(rule models.Rule, fieldType fieldType) *CriteriaError {
+	if rule.Operator == "exists" {
+		_, err := existsOperand(rule)
+		return err
+	}
+
+	switch fieldType {
+	case numberField:
+		_, err := numberOperands(rule)
+		return err
+	case moneyField:
+		_, err := moneyOperands(rule, models.DefaultCurrency)
+		return err
+	case stringField:
+		if rule.Operator == "regex" {
+			_, err := regexOperand(rule)
+			return err
+		}
+		_, err := stringOperands(rule)
+		return err
+	case dateField:
+		_, err := dateOperands(rule)
+		return err
+	}
+	return nil
+}
+
+func compareNumberRule(actual int, rule models.Rule) (bool, *CriteriaError) {
+	if err := checkOperator(numberField, rule.Operator); err != nil {
+		return false, err
+	}
+
+	if rule.Operator == "exists" {
+		// Number fields always have a value.
+		return compareExists(true, rule)
+	}
+
+	operands, err := numberOperands(rule)
+	if err != nil {
+		return false, err
+	}
+
+	switch rule.Operator {
+	case "in":
+		return containsInt(operands, actual), nil
+	case "not_in":
+		return !containsInt(operands, actual), nil
+	case "between":
+		return operands[0] <= actual && actual <= operands[1], nil
+	default:
+		passed, _ := compareInts(actual, rule.Operator, operands[0])
+		return passed, nil
+	}
+}
+
+func compareMoneyRule(actual models.Money, rule models.Rule) (bool, *CriteriaError) {
+	if err := checkOperator(moneyField, rule.Operator); err != nil {
+		return false, err
+	}
+
+	if rule.Operator == "exists" {
+		// Money fields always have a value.
+		return compareExists(true, rule)
+	}
+
+	operands, err := moneyOperands(rule, actual.CurrencyCode())
+	if err != nil {
+		return false, err
+	}
+
+	switch rule.Operator {
+	case "in":
+		return containsInt64(operands, actual.Amount), nil
+	case "not_in":
+		return !containsInt64(operands, actual.Amount), nil
+	case "between":
+		return operands[0] <= actual.Amount && actual.Amount <= operands[1], nil
+	default:
+		passed, _ := compareInt64s(actual.Amount, rule.Operator, operands[0])
+		return passed, nil
+	}
+}
+
+func compareStringRule(actual string, rule models.Rule) (bool, *CriteriaError) {
+	if err := checkOperator(stringField, rule.Operator); err != nil {
+		return false, err
+	}
+
+	switch rule.Operator {
+	case "exists":
+		return compareExists(actual != "", rule)
+	case "regex":
+		pattern, err := regexOperand(rule)
+		if err != nil {
+			return false, err
+		}
+		return pattern.MatchString(actual), nil
+	}
+
+	operands, err := stringOperands(rule)
+	if err != nil {
+		return false, err
+	}
+
+	if rule.IgnoreCase {
+		actual = strings.ToLower(actual)
+		for i := range operands {
+			operands[i] = strings.ToLower(operands[i])
+		}
+	}
+
+	switch rule.Operator {
+	case "in":
+		return contains(operands, actual), nil
+	case "not_in":
+		return !contains(operands, actual), nil
+	default:
+		passed, _ := compareStrings(actual, rule.Operator, operands[0])
+		return passed, nil
+	}
+}
+
+// compareDateField evaluates a rule on a date field, returning the value that
+// was compared: the date itself, or the units elapsed up to asOf when
+// rule.Elapsed is set.
+func compareDateField(date *time.Time, rule models.Rule, asOf time.Time) (interface{}, bool, *CriteriaError) {
+	if rule.Elapsed == "" {
+		passed, err := compareDateRule(date, rule)
+		return date, passed, err
+	}
+
+	if !contains(elapsedUnits, rule.Elapsed) {
+		return nil, false, &CriteriaError{Path: "elapsed", Message: fmt.Sprintf("unsupported unit %q", rule.Elapsed)}
+	}
+
+	if date == nil {
+		// Nothing has elapsed since a date that was never set, but a
+		// misconfigured rule is still reported.
+		if err := checkOperator(numberField, rule.Operator); err != nil {
+			return nil, false, err
+		}
+		return nil, false, checkOperands(rule, numberField)
+	}
+
+	elapsed := elapsedSince(*date, asOf, rule.Elapsed)
+	passed, err := compareNumberRule(elapsed, rule)
+	return elapsed, passed, err
+}
+
+func compareDateRule(actual *time.Time, rule models.Rule) (bool, *CriteriaError) {
+	if err := checkOperator(dateField, rule.Operator); err != nil {
+		return false, err
+	}
+
+	if rule.Operator == "exists" {
+		return compareExists(actual != nil, rule)
+	}
+
+	operands, err := dateOperands(rule)
+	if err != nil {
+		return false, err
+	}
+	if actual == nil {
+		return false, nil
+	}
+
+	date := calendarDate(*actual)
+	switch rule.Operator {
+	case "before":
+		return date.Before(operands[0]), nil
+	case "after":
+		return date.After(operands[0]), nil
+	case "on":
+		return date.Equal(operands[0]), nil
+	default: // between
+		return !date.Before(operands[0]) && !date.After(operands[1]), nil
+	}
+}
+
+// dateOperands decodes the rule's value into [from, to] for between and a
+// single date otherwise.
+func dateOperands(rule models.Rule) ([]time.Time, *CriteriaError) {
+	if rule.Operator != "between" {
+		date, err := parseDate(rule.Value)
+		if err != nil {
+			return nil, &CriteriaError{Path: "value", Message: err.Error()}
+		}
+		return []time.Time{date}, nil
+	}
+
+	values, ok := rule.Value.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, &CriteriaError{Path: "value", Message: "expected [from, to]"}
+	}
+	operands := make([]time.Time, 0, 2)
+	for i, value := range values {
+		date, err := parseDate(value)
+		if err != nil {
+			return nil, &CriteriaError{Path: fmt.Sprintf("value[%d]", i), Message: err.Error()}
+		}
+		operands = append(operands, date)
+	}
+	if operands[0].After(operands[1]) {
+		return nil, &CriteriaError{Path: "value", Message: "from must not be after to"}
+	}
+	return operands, nil
+}
+
+func parseDate(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected date")
+	}
+	date, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected date in YYYY-MM-DD format")
+	}
+	return date, nil
+}
+
+// calendarDate drops the time of day so dates compare by calendar day.
+func calendarDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// elapsedSince returns the whole number of days, months or years from date to
+// now. A month has elapsed once the same day of the month has been reached.
+func elapsedSince(date, now time.Time, unit string) int {
+	from, to := calendarDate(date), calendarDate(now)
+
+	switch unit {
+	case "days":
+		return int(to.Sub(from).Hours() / 24)
+	case "years":
+		return elapsedMonths(from, to) / 12
+	default:
+		return elapsedMonths(from, to)
+	}
+}
+
+func elapsedMonths(from, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() < from.Day() {
+		months--
+	}
+	return months
+}
+
+func compareExists(present bool, rule models.Rule) (bool, *CriteriaError) {
+	expected, err := existsOperand(rule)
+	if err != nil {
+		return false, err
+	}
+	return present == expected, nil
+}
+
+func existsOperand(rule models.Rule) (bool, *CriteriaError) {
+	switch value := rule.Value.(type) {
+	case nil:
+		return true, nil
+	case bool:
+		return value, nil
+	default:
+		return false, &CriteriaError{Path: "value", Message: "expected boolean"}
+	}
+}
+
+// numberOperands decodes the rule's value into the numbers its operator
+// compares against: a list for in/not_in, [min, max] for between and a single
+// number otherwise.
+func numberOperands(rule models.Rule) ([]int, *CriteriaError) {
+	switch rule.Operator {
+	case "in", "not_in":
+		values, ok := rule.Value.([]interface{})
+		if !ok {
+			return nil, &CriteriaError{Path: "value", Message: "expected array of numbers"}
+		}
+		operands := make([]int, 0, len(values))
+		for i, value := range values {
+			number, err := wholeNumber(value)
+			if err != nil {
+				return nil, &CriteriaError{Path: fmt.Sprintf("value[%d]", i), Message: err.Error()}
+			}
+			operands = append(operands, number)
+		}
+		return operands, nil
+	case "between":
+		values, ok := rule.Value.([]interface{})
+		if !ok || len(values) != 2 {
+			return nil, &CriteriaError{Path: "value", Message: "expected [min, max]"}
+		}
+		operands := make([]int, 0, 2)
+		for i, value := range values {
+			number, err := wholeNumber(value)
+			if err != nil {
+				return nil, &CriteriaError{Path: fmt.Sprintf("value[%d]", i), Message: err.Error()}
+			}
+			operands = append(operands, number)
+		}
+		if operands[0] > operands[1] {
+			return nil, &CriteriaError{Path: "value", Message: "min must not be greater than max"}
+		}
+		return operands, nil
+	default:
+		number, err := wholeNumber(rule.Value)
+		if err != nil {
+			return nil, &CriteriaError{Path: "value", Message: err.Error()}
+		}
+		return []int{number}, nil
+	}
+}
+
+// moneyOperands decodes the rule's value like numberOperands into amounts in
+// minor units, which must all be in the given currency.
+func moneyOperands(rule models.Rule, currency string) ([]int64, *CriteriaError) {
+	operand := func(value interface{}) (int64, error) {
+		var amount models.Money
+		switch v := value.(type) {
+		case float64:
+			if v != math.Trunc(v) {
+				return 0, fmt.Errorf("expected whole number of minor units")
+			}
+			if v < math.MinInt64 || v >= math.MaxInt64 {
+				return 0, fmt.Errorf("amount is out of range")
+			}
+			amount = models.NewMoney(int64(v))
+		case map[string]interface{}:
+			encoded, _ := json.Marshal(v)
+			if err := json.Unmarshal(encoded, &amount); err != nil {
+				return 0, fmt.Errorf("expected number of minor units or {amount, currency}")
+			}
+		default:
+			return 0, fmt.Errorf("expected number of minor units or {amount, currency}")
+		}
+		if amount.CurrencyCode() != currency {
+			return 0, fmt.Errorf("expected amount in %s, got %s", currency, amount.CurrencyCode())
+		}
+		return amount.Amount, nil
+	}
+
+	switch rule.Operator {
+	case "in", "not_in", "between":
+		values, ok := rule.Value.([]interface{})
+		if !ok {
+			if rule.Operator == "between" {
+				return nil, &CriteriaError{Path: "value", Message: "expected [min, max]"}
+			}
+			return nil, &CriteriaError{Path: "value", Message: "expected array of amounts"}
+		}
+		if rule.Operator == "between" && len(values) != 2 {
+			return nil, &CriteriaError{Path: "value", Message: "expected [min, max]"}
+		}
+		operands := make([]int64, 0, len(values))
+		for i, value := range values {
+			amount, err := operand(value)
+			if err != nil {
+				return nil, &CriteriaError{Path: fmt.Sprintf("value[%d]", i), Message: err.Error()}
+			}
+			operands = append(operands, amount)
+		}
+		if rule.Operator == "between" && operands[0] > operands[1] {
+			return nil, &CriteriaError{Path: "value", Message: "min must not be greater than max"}
+		}
+		return operands, nil
+	default:
+		amount, err := operand(rule.Value)
+		if err != nil {
+			return nil, &CriteriaError{Path: "value", Message: err.Error()}
+		}
+		return []int64{amount}, nil
+	}
+}
+
+func wholeNumber(value interface{}) (int, error) {
+	switch number := value.(type) {
+	case float64:
+		if number != math.Trunc(number) {
+			return 0, fmt.Errorf("expected whole number")
+		}
+		if number < math.MinInt || number >= math.MaxInt {
+			return 0, fmt.Errorf("number is out of range")
+		}
+		return int(number), nil
+	case int:
+		return number, nil
+	default:
+		return 0, fmt.Errorf("expected number")
+	}
+}
+
+// stringOperands decodes the rule's value into a list for in/not_in and a
+// single string otherwise.
+func stringOperands(rule models.Rule) ([]string, *CriteriaError) {
+	if rule.Operator != "in" && rule.Operator != "not_in" {
+		value, ok := rule.Value.(string)
+		if !ok {
+			return nil, &CriteriaError{Path: "value", Message: "expected string"}
+		}
+		return []string{value}, nil
+	}
+
+	values, ok := rule.Value.([]interface{})
+	if !ok {
+		return nil, &CriteriaError{Path: "value", Message: "expected array of strings"}
+	}
+	operands := make([]string, 0, len(values))
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, &CriteriaError{Path: fmt.Sprintf("value[%d]", i), Message: "expected string"}
+		}
+		operands = append(operands, s)
+	}
+	return operands, nil
+}
+
+func regexOperand(rule models.Rule) (*regexp.Regexp, *CriteriaError) {
+	pattern, ok := rule.Value.(string)
+	if !ok {
+		return nil, &CriteriaError{Path: "value", Message: "expected string"}
+	}
+	if rule.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, &CriteriaError{Path: "value", Message: fmt.Sprintf("invalid regular expression: %v", err)}
+	}
+	return compiled, nil
+}
+
+// compareInts reports whether a and b satisfy operator, and false for ok when
+// the operator is not supported.
+func compareInts(a int, operator string, b int) (result bool, ok bool) {
+	switch operator {
+	case "==":
+		return a == b, true
+	case "!=":
+		return a != b, true
+	case ">=":
+		return a >= b, true
+	case "<=":
+		return a <= b, true
+	case ">":
+		return a > b, true
+	case "<":
+		return a < b, true
+	default:
+		return false, false
+	}
+}
+
+func compareInt64s(a int64, operator string, b int64) (result bool, ok bool) {
+	switch operator {
+	case "==":
+		return a == b, true
+	case "!=":
+		return a != b, true
+	case ">=":
+		return a >= b, true
+	case "<=":
+		return a <= b, true
+	case ">":
+		return a > b, true
+	case "<":
+		return a < b, true
+	default:
+		return false, false
+	}
+}
+
+func compareStrings(a string, operator string, b string) (result bool, ok bool) {
+	switch operator {
+	case "==":
+		return a == b, true
+	case "!=":
+		return a != b, true
+	default:
+		return false, false
+	}
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt64(values []int64, value int64) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
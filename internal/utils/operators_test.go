@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareNumberRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		actual   int
+		operator string
+		value    interface{}
+		expected bool
+	}{
+		{"Equal", 5, "==", float64(5), true},
+		{"Not equal", 5, "!=", float64(5), false},
+		{"Not equal passes", 5, "!=", float64(6), true},
+		{"Greater than", 5, ">", float64(4), true},
+		{"Greater than or equal", 5, ">=", float64(5), true},
+		{"Less than", 5, "<", float64(5), false},
+		{"Less than or equal", 5, "<=", float64(5), true},
+		{"In list", 5, "in", []interface{}{float64(1), float64(5)}, true},
+		{"Not in list", 5, "in", []interface{}{float64(1), float64(2)}, false},
+		{"In empty list", 5, "in", []interface{}{}, false},
+		{"Not in", 5, "not_in", []interface{}{float64(1), float64(2)}, true},
+		{"Between lower bound", 18, "between", []interface{}{float64(18), float64(64)}, true},
+		{"Between upper bound", 64, "between", []interface{}{float64(18), float64(64)}, true},
+		{"Outside between", 65, "between", []interface{}{float64(18), float64(64)}, false},
+		{"Exists", 0, "exists", nil, true},
+		{"Not exists", 0, "exists", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passed, err := compareNumberRule(tt.actual, models.Rule{Operator: tt.operator, Value: tt.value})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, passed)
+		})
+	}
+}
+
+func TestCompareStringRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		actual     string
+		operator   string
+		value      interface{}
+		ignoreCase bool
+		expected   bool
+	}{
+		{"Equal", "unemployed", "==", "unemployed", false, true},
+		{"Equal is case-sensitive", "Unemployed", "==", "unemployed", false, false},
+		{"Equal ignoring case", "Unemployed", "==", "unemployed", true, true},
+		{"Not equal", "employed", "!=", "unemployed", false, true},
+		{"Not equal ignoring case", "UNEMPLOYED", "!=", "unemployed", true, false},
+		{"In list", "retrenched", "in", []interface{}{"unemployed", "retrenched"}, false, true},
+		{"In list ignoring case", "Retrenched", "in", []interface{}{"unemployed", "retrenched"}, true, true},
+		{"Not in list", "employed", "not_in", []interface{}{"unemployed", "retrenched"}, false, true},
+		{"Regex matches anywhere", "divorced", "regex", "vorc", false, true},
+		{"Anchored regex", "divorced", "regex", "^vorc", false, false},
+		{"Regex ignoring case", "Widowed", "regex", "^widow", true, true},
+		{"Exists", "single", "exists", nil, false, true},
+		{"Empty string does not exist", "", "exists", true, false, false},
+		{"Exists false", "", "exists", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passed, err := compareStringRule(tt.actual, models.Rule{Operator: tt.operator, Value: tt.value, IgnoreCase: tt.ignoreCase})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, passed)
+		})
+	}
+}
+
+func TestCompareDateRule(t *testing.T) {
+	lastEmployed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		actual   *time.Time
+		value    interface{}
+		expected bool
+	}{
+		{"Set date exists", &lastEmployed, nil, true},
+		{"Null date does not exist", nil, nil, false},
+		{"Null date with exists false", nil, false, true},
+		{"Set date with exists false", &lastEmployed, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passed, err := compareDateRule(tt.actual, models.Rule{Operator: "exists", Value: tt.value})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, passed)
+		})
+	}
+}
+
+func TestCompareDateField(t *testing.T) {
+	asOf := time.Date(2024, 7, 15, 10, 30, 0, 0, time.UTC)
+
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name           string
+		date           *time.Time
+		rule           models.Rule
+		expected       bool
+		expectedActual interface{}
+	}{
+		{
+			name:     "Before",
+			date:     date(1959, 12, 31),
+			rule:     models.Rule{Operator: "before", Value: "1960-01-01"},
+			expected: true,
+		},
+		{
+			name:     "Before is strict",
+			date:     date(1960, 1, 1),
+			rule:     models.Rule{Operator: "before", Value: "1960-01-01"},
+			expected: false,
+		},
+		{
+			name:     "After",
+			date:     date(1960, 1, 2),
+			rule:     models.Rule{Operator: "after", Value: "1960-01-01"},
+			expected: true,
+		},
+		{
+			name:     "On ignores time of day",
+			date:     func() *time.Time { d := time.Date(1960, 1, 1, 18, 0, 0, 0, time.UTC); return &d }(),
+			rule:     models.Rule{Operator: "on", Value: "1960-01-01"},
+			expected: true,
+		},
+		{
+			name:     "Between is inclusive",
+			date:     date(1959, 12, 31),
+			rule:     models.Rule{Operator: "between", Value: []interface{}{"1950-01-01", "1959-12-31"}},
+			expected: true,
+		},
+		{
+			name:     "Null date is not before",
+			date:     nil,
+			rule:     models.Rule{Operator: "before", Value: "1960-01-01"},
+			expected: false,
+		},
+		{
+			name:           "Six months elapsed",
+			date:           date(2024, 1, 15),
+			rule:           models.Rule{Elapsed: "months", Operator: ">=", Value: float64(6)},
+			expected:       true,
+			expectedActual: 6,
+		},
+		{
+			name:           "Just under six months elapsed",
+			date:           date(2024, 1, 16),
+			rule:           models.Rule{Elapsed: "months", Operator: ">=", Value: float64(6)},
+			expected:       false,
+			expectedActual: 5,
+		},
+		{
+			name:           "Days elapsed",
+			date:           date(2024, 7, 1),
+			rule:           models.Rule{Elapsed: "days", Operator: "==", Value: float64(14)},
+			expected:       true,
+			expectedActual: 14,
+		},
+		{
+			name:           "Years elapsed",
+			date:           date(2017, 7, 16),
+			rule:           models.Rule{Elapsed: "years", Operator: "<", Value: float64(7)},
+			expected:       true,
+			expectedActual: 6,
+		},
+		{
+			name:     "Null date has nothing elapsed",
+			date:     nil,
+			rule:     models.Rule{Elapsed: "months", Operator: ">=", Value: float64(6)},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, passed, err := compareDateField(tt.date, tt.rule, asOf)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, passed)
+			if tt.expectedActual != nil {
+				assert.Equal(t, tt.expectedActual, actual)
+			}
+		})
+	}
+}
+
+func TestCompareRuleErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		compare  func() (bool, *CriteriaError)
+		expected string
+	}{
+		{
+			name:     "Ordering on string field",
+			compare:  func() (bool, *CriteriaError) { return compareStringRule("a", models.Rule{Operator: ">", Value: "b"}) },
+			expected: `operator: unsupported operator ">", expected one of ==, !=, in, not_in, regex, exists`,
+		},
+		{
+			name: "Between with one bound",
+			compare: func() (bool, *CriteriaError) {
+				return compareNumberRule(1, models.Rule{Operator: "between", Value: []interface{}{float64(1)}})
+			},
+			expected: "value: expected [min, max]",
+		},
+		{
+			name: "In with scalar",
+			compare: func() (bool, *CriteriaError) {
+				return compareNumberRule(1, models.Rule{Operator: "in", Value: float64(1)})
+			},
+			expected: "value: expected array of numbers",
+		},
+		{
+			name: "Invalid regex",
+			compare: func() (bool, *CriteriaError) {
+				return compareStringRule("a", models.Rule{Operator: "regex", Value: "[a"})
+			},
+			expected: "value: invalid regular expression: error parsing regexp: missing closing ]: `[a`",
+		},
+		{
+			name: "Number out of range",
+			compare: func() (bool, *CriteriaError) {
+				return compareNumberRule(1, models.Rule{Operator: ">", Value: 1e20})
+			},
+			expected: "value: number is out of range",
+		},
+		{
+			name: "Amount out of range",
+			compare: func() (bool, *CriteriaError) {
+				return compareMoneyRule(models.NewMoney(1), models.Rule{Operator: "in", Value: []interface{}{float64(1), 1e20}})
+			},
+			expected: "value[1]: amount is out of range",
+		},
+		{
+			name: "Comparison on date field",
+			compare: func() (bool, *CriteriaError) {
+				return compareDateRule(nil, models.Rule{Operator: "<", Value: "2024-01-01"})
+			},
+			expected: `operator: unsupported operator "<", expected one of before, after, on, between, exists`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passed, err := tt.compare()
+			assert.False(t, passed)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.expected, err.Error())
+			}
+		})
+	}
+}
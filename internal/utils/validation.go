@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+)
+
+// MaxAge is the oldest age a date of birth may imply.
+const MaxAge = 130
+
+// vocabularies are the controlled vocabularies of the applicant and household
+// member fields, keyed by field name.
+var vocabularies = map[string][]string{
+	"employment_status": models.EmploymentStatuses,
+	"sex":               models.Sexes,
+	"marital_status":    models.MaritalStatuses,
+	"disability_status": models.DisabilityStatuses,
+	"relation":          models.Relations,
+	"income_period":     models.IncomePeriods,
+}
+
+// Vocabulary returns the controlled vocabulary of a field, or nil if the field
+// accepts any value.
+func Vocabulary(field string) []string {
+	return vocabularies[field]
+}
+
+// Validator collects the problems found in a request, addressed by the path of
+// the field, so they can all be reported at once.
+type Validator struct {
+	Now    time.Time
+	Errors []CriteriaError
+}
+
+// NewValidator returns a Validator that checks dates against the current time.
+func NewValidator() *Validator {
+	return &Validator{Now: Clock()}
+}
+
+// Valid reports whether no problems were found.
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// Fail records a problem with the field at path.
+func (v *Validator) Fail(path, message string) {
+	v.Errors = append(v.Errors, CriteriaError{Path: path, Message: message})
+}
+
+// Required checks that a field has a value.
+func (v *Validator) Required(path, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Fail(path, "is required")
+		return false
+	}
+	return true
+}
+
+// OneOf checks that a field holds a value from the vocabulary of field. An
+// empty value is left to Required.
+func (v *Validator) OneOf(path, field, value string) {
+	vocabulary := Vocabulary(field)
+	if value == "" || vocabulary == nil || contains(vocabulary, value) {
+		return
+	}
+	v.Fail(path, fmt.Sprintf("unknown %s %q, expected one of %s", field, value, strings.Join(vocabulary, ", ")))
+}
+
+// NonNegative checks that a number is not negative.
+func (v *Validator) NonNegative(path string, value int) {
+	if value < 0 {
+		v.Fail(path, "must not be negative")
+	}
+}
+
+// Money checks that an amount is not negative and is in the default currency.
+func (v *Validator) Money(path string, value models.Money) {
+	if value.CurrencyCode() != models.DefaultCurrency {
+		v.Fail(path, fmt.Sprintf("unsupported currency %q, expected %s", value.CurrencyCode(), models.DefaultCurrency))
+	}
+	if value.Amount < 0 {
+		v.Fail(path, "must not be negative")
+	}
+}
+
+// Date parses a YYYY-MM-DD date that must not be in the future.
+func (v *Validator) Date(path, value string) (time.Time, bool) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		v.Fail(path, "must be a date in YYYY-MM-DD format")
+		return time.Time{}, false
+	}
+	if date.After(v.Now) {
+		v.Fail(path, "must not be in the future")
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// DateOfBirth parses a required date of birth, which must not be in the future
+// or imply an age over MaxAge.
+func (v *Validator) DateOfBirth(path, value string) (time.Time, bool) {
+	if !v.Required(path, value) {
+		return time.Time{}, false
+	}
+	date, ok := v.Date(path, value)
+	if !ok {
+		return time.Time{}, false
+	}
+	if calculateAge(date, v.Now) > MaxAge {
+		v.Fail(path, fmt.Sprintf("implies an age over %d", MaxAge))
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bensiauu/financial-assistance-scheme/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	v := &Validator{Now: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+
+	dateOfBirth, ok := v.DateOfBirth("date_of_birth", "1990-01-01")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), dateOfBirth)
+
+	_, ok = v.DateOfBirth("missing", "")
+	assert.False(t, ok)
+	_, ok = v.DateOfBirth("format", "01-01-1990")
+	assert.False(t, ok)
+	_, ok = v.DateOfBirth("future", "2024-06-02")
+	assert.False(t, ok)
+	_, ok = v.DateOfBirth("too_old", "1893-06-01")
+	assert.False(t, ok)
+	_, ok = v.DateOfBirth("oldest", "1893-06-02")
+	assert.True(t, ok)
+
+	v.OneOf("sex", "sex", "female")
+	v.OneOf("empty", "sex", "")
+	v.OneOf("unrestricted", "name", "anything")
+	v.OneOf("relation", "relation", "Son")
+	v.NonNegative("income", 0)
+	v.NonNegative("number_of_children", -1)
+	v.Money("income", models.NewMoney(150000))
+	v.Money("household[0].income", models.Money{Amount: 100, Currency: "USD"})
+
+	assert.Equal(t, []CriteriaError{
+		{Path: "missing", Message: "is required"},
+		{Path: "format", Message: "must be a date in YYYY-MM-DD format"},
+		{Path: "future", Message: "must not be in the future"},
+		{Path: "too_old", Message: "implies an age over 130"},
+		{Path: "relation", Message: `unknown relation "Son", expected one of spouse, son, daughter, child, father, mother, sibling, grandparent, grandchild, other`},
+		{Path: "number_of_children", Message: "must not be negative"},
+		{Path: "household[0].income", Message: `unsupported currency "USD", expected SGD`},
+	}, v.Errors)
+	assert.False(t, v.Valid())
+}
@@ -15,14 +15,27 @@ type Administrator struct {
 	Name         string    `gorm:"size:255;not null"`
 	Email        string    `gorm:"size:255;unique;not null"`
 	PasswordHash string    `gorm:"size:255;not null"`
+	Role         string    `gorm:"size:20;not null;default:'caseworker'"` // One of Roles
 	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
 
+// Administrator roles, which decide what an administrator may do.
+const (
+	RoleSuperAdmin    = "super_admin"    // Everything, including managing administrators
+	RoleSchemeManager = "scheme_manager" // Manages schemes
+	RoleCaseworker    = "caseworker"     // Manages applicants and applications
+	RoleAuditor       = "auditor"        // Read-only
+)
+
+// Roles are the roles an administrator may have.
+var Roles = []string{RoleSuperAdmin, RoleSchemeManager, RoleCaseworker, RoleAuditor}
+
 type AdministratorResponse struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -31,14 +44,17 @@ type AdministratorResponse struct {
 type Applicant struct {
 	ID               uuid.UUID         `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
 	Name             string            `gorm:"size:255;not null"`
+	NationalID       *string           `gorm:"size:9;uniqueIndex"` // NRIC or FIN, unique across applicants
 	EmploymentStatus string            `gorm:"column:employment_status;size:50;not null"`
 	Sex              string            `gorm:"size:10;not null"`
 	DateOfBirth      time.Time         `gorm:"not null"`
-	LastEmployed     *time.Time        `gorm:"type:date"` // Nullable date field
-	Income           int               `gorm:"default:0;not null"`
+	LastEmployed     *time.Time        `gorm:"type:date"`                          // Nullable date field
+	Income           Money             `gorm:"type:jsonb;not null"`                // Per IncomePeriod
+	IncomePeriod     string            `gorm:"size:10;not null;default:'monthly'"` // IncomeMonthly or IncomeAnnual
 	MaritalStatus    string            `gorm:"size:50;not null"`
 	DisabilityStatus string            `gorm:"size:50;not null"`
 	NumberOfChildren int               `gorm:"not null"`
+	Version          int               `gorm:"default:1;not null"` // Incremented on every change, for optimistic concurrency
 	CreatedAt        time.Time         `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt        time.Time         `gorm:"default:CURRENT_TIMESTAMP"`
 	Household        []HouseholdMember `gorm:"foreignkey:ApplicantID"` // One-to-many relationship
@@ -47,37 +63,149 @@ type Applicant struct {
 type ApplicantResponse struct {
 	ID               uuid.UUID         `json:"id"`
 	Name             string            `json:"name"`
+	NationalID       string            `json:"national_id,omitempty"` // Masked unless the caller may see national IDs
 	EmploymentStatus string            `json:"employment_status"`
 	Sex              string            `json:"sex"`
 	DateOfBirth      time.Time         `json:"date_of_birth"`
 	LastEmployed     *time.Time        `json:"last_employed"`
-	Income           int               `json:"income"`
+	Income           Money             `json:"income"`
+	IncomePeriod     string            `json:"income_period"`
+	HouseholdIncome  Money             `json:"household_income"`  // Monthly
+	PerCapitaIncome  Money             `json:"per_capita_income"` // Monthly
 	MaritalStatus    string            `json:"marital_status"`
 	DisabilityStatus string            `json:"disability_status"`
 	NumberOfChildren int               `json:"number_of_children"`
 	Household        []HouseholdMember `json:"household"`
+	Version          int               `json:"version"`
+}
+
+// DuplicateCandidate is a pair of applicants the duplicate detection job
+// suspects are the same person. ApplicantID is the lower of the two IDs.
+type DuplicateCandidate struct {
+	ID               uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	ApplicantID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_duplicate_candidates_pair"`
+	DuplicateID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_duplicate_candidates_pair;index"`
+	Score            float64   `gorm:"not null"` // Overall likelihood from 0 to 1
+	NameSimilarity   float64   `gorm:"not null"` // Trigram similarity of the names
+	SameDateOfBirth  bool      `gorm:"not null"`
+	HouseholdOverlap int       `gorm:"not null"` // Number of household members in common
+	DetectedAt       time.Time `gorm:"autoCreateTime"`
+}
+
+// ApplicantMerge records an applicant merged into another. The merged
+// applicant's record is kept as it was before the merge.
+type ApplicantMerge struct {
+	ID                uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	SurvivorID        uuid.UUID       `gorm:"type:uuid;not null;index"`
+	MergedID          uuid.UUID       `gorm:"type:uuid;not null"`
+	MergedApplicant   json.RawMessage `gorm:"type:jsonb;not null"`
+	MergedBy          string          `gorm:"size:255;not null"` // ID of the administrator from the JWT subject
+	ApplicationsMoved int             `gorm:"not null"`
+	HouseholdMoved    int             `gorm:"not null"`
+	CreatedAt         time.Time       `gorm:"autoCreateTime"`
 }
 
+// Controlled vocabularies for applicant and household member attributes. Scheme
+// rules on these fields are checked against the same lists.
+var (
+	EmploymentStatuses = []string{"employed", "self_employed", "unemployed", "retired", "homemaker", "preschool", "primary_school", "secondary_school", "tertiary"}
+	Sexes              = []string{"male", "female"}
+	MaritalStatuses    = []string{"single", "married", "divorced", "separated", "widowed"}
+	DisabilityStatuses = []string{"none", "disabled"}
+	Relations          = []string{"spouse", "son", "daughter", "child", "father", "mother", "sibling", "grandparent", "grandchild", "other"}
+)
+
 // HouseholdMember represents a member of the applicant's household.
 type HouseholdMember struct {
 	ID               uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	ApplicantID      uuid.UUID `gorm:"type:uuid;not null"` // Foreign key to Applicant
+	ApplicantID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_household_members_applicant_id_national_id"` // Foreign key to Applicant
 	Name             string    `gorm:"size:255;not null"`
-	Relation         string    `gorm:"size:50;not null"` // Relationship to the applicant
+	NationalID       *string   `gorm:"size:9;uniqueIndex:idx_household_members_applicant_id_national_id"` // NRIC or FIN, unique within the household
+	Relation         string    `gorm:"size:50;not null"`                                                  // Relationship to the applicant
 	DateOfBirth      time.Time `gorm:"not null"`
 	EmploymentStatus string    `gorm:"size:50;not null"`
+	Income           Money     `gorm:"type:jsonb;not null"`                // Per IncomePeriod
+	IncomePeriod     string    `gorm:"size:10;not null;default:'monthly'"` // IncomeMonthly or IncomeAnnual
 	CreatedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
 
+// Income periods. Incomes are compared and summed per month.
+const (
+	IncomeMonthly = "monthly"
+	IncomeAnnual  = "annual"
+)
+
+// IncomePeriods are the periods an income may be given for.
+var IncomePeriods = []string{IncomeMonthly, IncomeAnnual}
+
+// MonthlyIncome converts an income per period to an income per month,
+// rounding annual incomes to the nearest minor unit.
+func MonthlyIncome(income Money, period string) Money {
+	if period == IncomeAnnual {
+		return income.Div(12)
+	}
+	return income
+}
+
+// MonthlyIncome is the applicant's own income per month.
+func (a Applicant) MonthlyIncome() Money {
+	return MonthlyIncome(a.Income, a.IncomePeriod)
+}
+
+// MonthlyIncome is the member's income per month.
+func (m HouseholdMember) MonthlyIncome() Money {
+	return MonthlyIncome(m.Income, m.IncomePeriod)
+}
+
+// HouseholdIncome is the monthly income of the applicant and every member of
+// their household, which must have been loaded.
+func (a Applicant) HouseholdIncome() Money {
+	income := a.MonthlyIncome()
+	for _, member := range a.Household {
+		income = income.Add(member.MonthlyIncome())
+	}
+	return income
+}
+
+// PerCapitaIncome is the household income shared equally between the
+// applicant and their household members, rounded to the nearest minor unit.
+func (a Applicant) PerCapitaIncome() Money {
+	return a.HouseholdIncome().Div(len(a.Household) + 1)
+}
+
+// Rule compares a single applicant field against a value; IgnoreCase makes
+// string comparisons case-insensitive. On date fields, Elapsed ("days",
+// "months" or "years") compares the whole number of units elapsed since the
+// date instead of the date itself. Rules on the
+// "household" field instead apply Quantifier ("any", "all" or "count") to the
+// household members matching Member; for "count" the number of matching
+// members is compared using Operator and Value. "all" holds for an empty
+// household.
 type Rule struct {
-	Field    string      `json:"field"`
-	Operator string      `json:"operator"`
-	Value    interface{} `json:"value"`
+	Field      string      `json:"field"`
+	Operator   string      `json:"operator"`
+	Value      interface{} `json:"value"`
+	IgnoreCase bool        `json:"ignore_case,omitempty"`
+	Elapsed    string      `json:"elapsed,omitempty"`
+	Quantifier string      `json:"quantifier,omitempty"`
+	Member     *Criteria   `json:"member,omitempty"`
 }
 
+// Criteria is a group of eligibility conditions. Every rule in Rules and
+// every group in All must hold, at least one group in Any must hold (when Any
+// is non-empty) and the group in Not must fail (when set). Groups can be nested
+// to any depth; a flat list of rules keeps its original AND semantics.
 type Criteria struct {
-	Rules []Rule `json:"rules"`
+	Rules []Rule     `json:"rules"`
+	All   []Criteria `json:"all,omitempty"`
+	Any   []Criteria `json:"any,omitempty"`
+	Not   *Criteria  `json:"not,omitempty"`
+
+	// raw and decodeErr are set when the stored JSON could not be decoded, so
+	// that one malformed scheme does not fail every query that loads schemes.
+	raw       json.RawMessage
+	decodeErr error
 }
 
 func (c *Criteria) Scan(value interface{}) error {
@@ -86,27 +214,195 @@ func (c *Criteria) Scan(value interface{}) error {
 		return fmt.Errorf("type assertion to []byte failed")
 	}
 
-	return json.Unmarshal(bytes, &c)
+	if err := json.Unmarshal(bytes, &c); err != nil {
+		*c = Criteria{raw: append(json.RawMessage(nil), bytes...), decodeErr: err}
+	}
+	return nil
 }
 
 func (c Criteria) Value() (driver.Value, error) {
 	return json.Marshal(c)
 }
 
+// MarshalJSON writes criteria that failed to decode back out unchanged.
+func (c Criteria) MarshalJSON() ([]byte, error) {
+	if c.decodeErr != nil {
+		return c.raw, nil
+	}
+	type criteria Criteria
+	return json.Marshal(criteria(c))
+}
+
+// Err returns the error encountered decoding the stored criteria, if any.
+func (c Criteria) Err() error {
+	return c.decodeErr
+}
+
+// Benefits describes what a scheme pays out. Cash amounts are one-off unless
+// they are part of Monthly; Cap limits the total cash paid over the scheme,
+// including per-child amounts and every monthly payout.
+type Benefits struct {
+	Description string         `json:"description,omitempty"`
+	Amount      Money          `json:"amount"`              // One-off cash amount
+	PerChild    *PerChild      `json:"per_child,omitempty"` // One-off cash amount for each eligible child
+	Monthly     *MonthlyPayout `json:"monthly,omitempty"`   // Recurring monthly cash payout
+	Vouchers    []Voucher      `json:"vouchers,omitempty"`
+	Cap         Money          `json:"cap"` // Maximum total cash; zero means uncapped
+
+	// raw and decodeErr are set when the stored JSON could not be decoded.
+	raw       json.RawMessage
+	decodeErr error
+}
+
+// PerChild pays Amount for each child in the applicant's household. Children
+// aged MaxAge or older are not counted, and at most MaxChildren are paid for;
+// a zero limit means no limit.
+type PerChild struct {
+	Amount      Money `json:"amount"`
+	MaxAge      int   `json:"max_age,omitempty"`
+	MaxChildren int   `json:"max_children,omitempty"`
+}
+
+// MonthlyPayout pays Amount every month for Months months.
+type MonthlyPayout struct {
+	Amount Money `json:"amount"`
+	Months int   `json:"months"`
+}
+
+// Voucher is a non-cash benefit, e.g. {"type": "groceries", "value": 5000, "quantity": 4}.
+type Voucher struct {
+	Type     string `json:"type"`
+	Value    Money  `json:"value"`
+	Quantity int    `json:"quantity"`
+}
+
+func (b *Benefits) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("type assertion to []byte failed")
+	}
+
+	if err := json.Unmarshal(bytes, &b); err != nil {
+		*b = Benefits{raw: append(json.RawMessage(nil), bytes...), decodeErr: err}
+	}
+	return nil
+}
+
+func (b Benefits) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+// MarshalJSON writes benefits that failed to decode back out unchanged.
+func (b Benefits) MarshalJSON() ([]byte, error) {
+	if b.decodeErr != nil {
+		return b.raw, nil
+	}
+	type benefits Benefits
+	return json.Marshal(benefits(b))
+}
+
+// Err returns the error encountered decoding the stored benefits, if any.
+func (b Benefits) Err() error {
+	return b.decodeErr
+}
+
+// Entitlement is the concrete benefit an applicant receives from a scheme.
+// OneOff and Monthly are before the scheme's cap; Total is the cash paid over
+// the scheme after the cap is applied.
+type Entitlement struct {
+	OneOff   Money     `json:"one_off"`
+	Monthly  Money     `json:"monthly"`
+	Months   int       `json:"months"`
+	Children int       `json:"children"` // Number of children per-child amounts were paid for
+	Vouchers []Voucher `json:"vouchers"`
+	Total    Money     `json:"total"`
+	Capped   bool      `json:"capped"`
+}
+
+func (e *Entitlement) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, &e)
+}
+
+func (e Entitlement) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
 type Scheme struct {
-	ID        uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	Name      string          `gorm:"size:255;not null"`   // Name of the scheme
-	Criteria  Criteria        `gorm:"type:jsonb;not null"` // Criteria for eligibility (stored as JSONB)
-	Benefits  json.RawMessage `gorm:"type:jsonb;not null"` // Benefits provided by the scheme (stored as JSONB)
-	CreatedAt time.Time       `gorm:"autoCreateTime"`      // Timestamp of when the scheme was created
-	UpdatedAt time.Time       `gorm:"autoUpdateTime"`      // Timestamp of when the scheme was last updated
+	ID               uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	Name             string     `gorm:"size:255;not null"`                           // Name of the scheme
+	Criteria         Criteria   `gorm:"type:jsonb;not null"`                         // Criteria for eligibility (stored as JSONB)
+	Benefits         Benefits   `gorm:"type:jsonb;not null"`                         // Benefits provided by the scheme (stored as JSONB)
+	Version          int        `gorm:"not null;default:1"`                          // Current version of the criteria and benefits
+	OpensAt          *time.Time `gorm:"type:timestamptz" json:"opens_at"`            // Applications are accepted from this time; nil means already open
+	ClosesAt         *time.Time `gorm:"type:timestamptz" json:"closes_at"`           // Applications are accepted until this time; nil means never closes
+	MaxBeneficiaries int        `gorm:"not null;default:0" json:"max_beneficiaries"` // Maximum number of granted applications; zero means unlimited
+	Budget           Money      `gorm:"type:jsonb;not null"`                         // Maximum total entitlement granted; zero means unlimited
+	CreatedAt        time.Time  `gorm:"autoCreateTime"`                              // Timestamp of when the scheme was created
+	UpdatedAt        time.Time  `gorm:"autoUpdateTime"`                              // Timestamp of when the scheme was last updated
 }
 
+// SchemeVersion is an immutable snapshot of a scheme's criteria and benefits.
+// A new version is recorded every time either of them changes.
+type SchemeVersion struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	SchemeID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_scheme_versions_scheme_id_version"`
+	Version   int       `gorm:"not null;uniqueIndex:idx_scheme_versions_scheme_id_version"`
+	Criteria  Criteria  `gorm:"type:jsonb;not null"`
+	Benefits  Benefits  `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Application statuses. The allowed transitions between them are enforced by
+// the applications handlers.
+const (
+	ApplicationDraft       = "draft"
+	ApplicationSubmitted   = "submitted"
+	ApplicationUnderReview = "under_review"
+	ApplicationApproved    = "approved"
+	ApplicationRejected    = "rejected"
+	ApplicationDisbursed   = "disbursed"
+	ApplicationWithdrawn   = "withdrawn"
+	ApplicationAppealed    = "appealed"
+)
+
+// OpenApplicationStatuses are the statuses of applications awaiting a decision.
+var OpenApplicationStatuses = []string{ApplicationDraft, ApplicationSubmitted, ApplicationUnderReview, ApplicationAppealed}
+
+// GrantedApplicationStatuses are the statuses of applications that count
+// towards a scheme's beneficiaries and budget.
+var GrantedApplicationStatuses = []string{ApplicationApproved, ApplicationDisbursed}
+
+// ActiveApplicationStatuses are the statuses of applications that are still in
+// progress or have been granted. An applicant may have at most one active
+// application per scheme.
+var ActiveApplicationStatuses = []string{ApplicationDraft, ApplicationSubmitted, ApplicationUnderReview, ApplicationApproved, ApplicationAppealed}
+
 type Application struct {
-	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	ApplicantID uuid.UUID `gorm:"type:uuid;not null"`                 // Foreign key to applicants
-	SchemeID    uuid.UUID `gorm:"type:uuid;not null"`                 // Foreign key to schemes
-	Status      string    `gorm:"size:50;not null;default:'pending'"` // Status of the application
-	CreatedAt   time.Time `gorm:"type:timestamptz;default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time `gorm:"type:timestamptz;default:CURRENT_TIMESTAMP"`
+	ID              uuid.UUID    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ApplicantID     uuid.UUID    `gorm:"type:uuid;not null"`                   // Foreign key to applicants
+	SchemeID        uuid.UUID    `gorm:"type:uuid;not null"`                   // Foreign key to schemes
+	SchemeVersion   int          `gorm:"not null;default:1"`                   // Scheme version the application was evaluated against
+	Status          string       `gorm:"size:50;not null;default:'submitted'"` // Status of the application
+	StatusReason    string       `gorm:"type:text"`                            // Reason given for the latest status change, required on rejection
+	Entitlement     *Entitlement `gorm:"type:jsonb"`                           // Benefits granted, set when the application is approved
+	IneligibleSince *time.Time   `gorm:"type:timestamptz"`                     // Set while the applicant no longer qualifies for an open application
+	CreatedAt       time.Time    `gorm:"type:timestamptz;default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time    `gorm:"type:timestamptz;default:CURRENT_TIMESTAMP"`
+}
+
+// ApplicationStatusChange is an append-only record of a change to an
+// application's status. The first record of an application has no FromStatus.
+type ApplicationStatusChange struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ApplicationID uuid.UUID `gorm:"type:uuid;not null;index"`
+	FromStatus    string    `gorm:"size:50"`
+	ToStatus      string    `gorm:"size:50;not null"`
+	ChangedBy     string    `gorm:"size:255;not null"` // ID of the administrator from the JWT subject
+	Remark        string    `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
 }
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// DefaultCurrency is the currency of amounts given without one.
+var DefaultCurrency = "SGD"
+
+// Money is an exact amount of a currency, counted in its minor unit (cents
+// for SGD) so that sums and comparisons never round.
+type Money struct {
+	Amount   int64  `json:"amount"`   // In minor units
+	Currency string `json:"currency"` // ISO 4217 code
+}
+
+// NewMoney returns an amount of minor units in DefaultCurrency.
+func NewMoney(amount int64) Money {
+	return Money{Amount: amount, Currency: DefaultCurrency}
+}
+
+// CurrencyCode returns the currency, which defaults to DefaultCurrency for the
+// zero value.
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add returns the sum of two amounts. It panics if they are in different
+// currencies.
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: m.CurrencyCode()}
+}
+
+// Sub returns the difference of two amounts. It panics if they are in
+// different currencies.
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: m.CurrencyCode()}
+}
+
+func (m Money) mustMatch(other Money) {
+	if m.CurrencyCode() != other.CurrencyCode() {
+		panic(fmt.Sprintf("money: mismatched currencies %s and %s", m.CurrencyCode(), other.CurrencyCode()))
+	}
+}
+
+// Mul returns the amount multiplied by n.
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.CurrencyCode()}
+}
+
+// Div returns the amount divided by n, rounded to the nearest minor unit with
+// halves rounded away from zero. n must be positive.
+func (m Money) Div(n int) Money {
+	half := int64(n) / 2
+	if m.Amount < 0 {
+		return Money{Amount: (m.Amount - half) / int64(n), Currency: m.CurrencyCode()}
+	}
+	return Money{Amount: (m.Amount + half) / int64(n), Currency: m.CurrencyCode()}
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%d %s", m.Amount, m.CurrencyCode())
+}
+
+// MarshalJSON writes {"amount": 1250, "currency": "SGD"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	type money Money
+	return json.Marshal(money{Amount: m.Amount, Currency: m.CurrencyCode()})
+}
+
+// UnmarshalJSON reads {"amount": 1250, "currency": "SGD"}, or a bare whole
+// number of minor units in DefaultCurrency as amounts were written before
+// they had a currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] != '{' {
+		var amount float64
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return fmt.Errorf("money must be a whole number of minor units or an object with amount and currency")
+		}
+		if amount != math.Trunc(amount) {
+			return fmt.Errorf("money must be a whole number of minor units")
+		}
+		if amount < math.MinInt64 || amount >= math.MaxInt64 {
+			return fmt.Errorf("money is out of range")
+		}
+		*m = NewMoney(int64(amount))
+		return nil
+	}
+
+	type money Money
+	var decoded money
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*m = Money{Amount: decoded.Amount, Currency: Money(decoded).CurrencyCode()}
+	return nil
+}
+
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return m.UnmarshalJSON(v)
+	case string:
+		return m.UnmarshalJSON([]byte(v))
+	case int64:
+		*m = NewMoney(v)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into money", value)
+	}
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
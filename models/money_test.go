@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoneyArithmetic(t *testing.T) {
+	assert.Equal(t, NewMoney(1500), NewMoney(1000).Add(NewMoney(500)))
+	assert.Equal(t, NewMoney(500), NewMoney(1000).Sub(NewMoney(500)))
+	// The zero value is in DefaultCurrency.
+	assert.Equal(t, NewMoney(1000), NewMoney(1000).Add(Money{}))
+
+	usd := Money{Amount: 500, Currency: "USD"}
+	assert.PanicsWithValue(t, "money: mismatched currencies SGD and USD", func() { NewMoney(1000).Add(usd) })
+	assert.PanicsWithValue(t, "money: mismatched currencies SGD and USD", func() { NewMoney(1000).Sub(usd) })
+}
+
+func TestMoneyDiv(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		n        int
+		expected int64
+	}{
+		{10, 2, 5},
+		{5, 2, 3},
+		{4, 3, 1},
+		{5, 3, 2},
+		{-10, 2, -5},
+		{-5, 2, -3},
+		{-4, 3, -1},
+		{-5, 3, -2},
+		{0, 12, 0},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, NewMoney(tt.expected), NewMoney(tt.amount).Div(tt.n), "%d / %d", tt.amount, tt.n)
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	var m Money
+	assert.NoError(t, m.UnmarshalJSON([]byte(`1250`)))
+	assert.Equal(t, NewMoney(1250), m)
+	assert.EqualError(t, m.UnmarshalJSON([]byte(`12.5`)), "money must be a whole number of minor units")
+	assert.EqualError(t, m.UnmarshalJSON([]byte(`1e20`)), "money is out of range")
+}
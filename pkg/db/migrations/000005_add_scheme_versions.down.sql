@@ -0,0 +1,7 @@
+ALTER TABLE applications
+DROP COLUMN scheme_version;
+
+DROP TABLE IF EXISTS scheme_versions;
+
+ALTER TABLE schemes
+DROP COLUMN version;
@@ -0,0 +1,19 @@
+ALTER TABLE schemes
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Immutable history of scheme criteria and benefits
+CREATE TABLE scheme_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    scheme_id UUID NOT NULL REFERENCES schemes(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    criteria JSONB NOT NULL,
+    benefits JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT idx_scheme_versions_scheme_id_version UNIQUE (scheme_id, version)
+);
+
+INSERT INTO scheme_versions (scheme_id, version, criteria, benefits, created_at)
+SELECT id, 1, criteria, benefits, created_at FROM schemes;
+
+ALTER TABLE applications
+ADD COLUMN scheme_version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+ALTER TABLE applications
+DROP COLUMN entitlement;
@@ -0,0 +1,5 @@
+ALTER TABLE applications
+DROP COLUMN status_reason,
+ALTER COLUMN status DROP DEFAULT;
+
+UPDATE applications SET status = 'pending' WHERE status IN ('draft', 'submitted', 'under_review');
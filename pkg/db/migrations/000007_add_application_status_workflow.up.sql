@@ -0,0 +1,6 @@
+-- Applications created before the status workflow were all "pending" review
+UPDATE applications SET status = 'submitted' WHERE status = 'pending';
+
+ALTER TABLE applications
+ALTER COLUMN status SET DEFAULT 'submitted',
+ADD COLUMN status_reason TEXT;
@@ -0,0 +1,2 @@
+DROP TABLE application_status_changes;
+DROP FUNCTION reject_application_status_change_update();
@@ -0,0 +1,26 @@
+-- Append-only record of application status changes
+CREATE TABLE application_status_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    application_id UUID NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    changed_by VARCHAR(255) NOT NULL,
+    remark TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_application_status_changes_application_id ON application_status_changes (application_id);
+
+CREATE FUNCTION reject_application_status_change_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'application_status_changes is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER application_status_changes_append_only
+BEFORE UPDATE ON application_status_changes
+FOR EACH ROW EXECUTE FUNCTION reject_application_status_change_update();
+
+-- Start the history of existing applications from their current status
+INSERT INTO application_status_changes (application_id, to_status, changed_by, remark, created_at)
+SELECT id, status, 'system', status_reason, updated_at FROM applications;
@@ -0,0 +1,2 @@
+ALTER TABLE applications
+ADD COLUMN ineligible_since TIMESTAMPTZ;
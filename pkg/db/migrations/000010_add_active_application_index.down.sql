@@ -0,0 +1 @@
+DROP INDEX idx_applications_active_applicant_scheme;
@@ -0,0 +1,23 @@
+-- Keep only the latest active application of each applicant for a scheme
+WITH duplicates AS (
+    SELECT id, status
+    FROM (
+        SELECT id, status, ROW_NUMBER() OVER (PARTITION BY applicant_id, scheme_id ORDER BY created_at DESC, id) AS position
+        FROM applications
+        WHERE status IN ('draft', 'submitted', 'under_review', 'approved', 'appealed')
+    ) ranked
+    WHERE position > 1
+), withdrawn AS (
+    UPDATE applications
+    SET status = 'withdrawn', status_reason = 'Duplicate application', updated_at = NOW()
+    FROM duplicates
+    WHERE applications.id = duplicates.id
+    RETURNING applications.id
+)
+INSERT INTO application_status_changes (application_id, from_status, to_status, changed_by, remark)
+SELECT duplicates.id, duplicates.status, 'withdrawn', 'system', 'Duplicate application'
+FROM duplicates JOIN withdrawn ON withdrawn.id = duplicates.id;
+
+-- At most one active application per applicant and scheme
+CREATE UNIQUE INDEX idx_applications_active_applicant_scheme ON applications (applicant_id, scheme_id)
+WHERE status IN ('draft', 'submitted', 'under_review', 'approved', 'appealed');
@@ -0,0 +1,5 @@
+ALTER TABLE schemes
+DROP COLUMN opens_at,
+DROP COLUMN closes_at,
+DROP COLUMN max_beneficiaries,
+DROP COLUMN budget;
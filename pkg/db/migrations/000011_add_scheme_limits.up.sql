@@ -0,0 +1,5 @@
+ALTER TABLE schemes
+ADD COLUMN opens_at TIMESTAMPTZ,
+ADD COLUMN closes_at TIMESTAMPTZ,
+ADD COLUMN max_beneficiaries INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN budget INTEGER NOT NULL DEFAULT 0;
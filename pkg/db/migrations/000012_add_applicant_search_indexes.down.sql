@@ -0,0 +1,4 @@
+DROP INDEX idx_household_members_applicant_id;
+DROP INDEX idx_applicants_date_of_birth;
+DROP INDEX idx_household_members_name_trgm;
+DROP INDEX idx_applicants_name_trgm;
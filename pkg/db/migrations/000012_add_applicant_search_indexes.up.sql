@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram indexes back fuzzy (%) and substring (ILIKE) name searches
+CREATE INDEX idx_applicants_name_trgm ON applicants USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_household_members_name_trgm ON household_members USING GIN (name gin_trgm_ops);
+
+CREATE INDEX idx_applicants_date_of_birth ON applicants (date_of_birth);
+CREATE INDEX idx_household_members_applicant_id ON household_members (applicant_id);
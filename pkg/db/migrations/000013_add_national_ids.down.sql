@@ -0,0 +1,5 @@
+ALTER TABLE household_members
+DROP COLUMN national_id;
+
+ALTER TABLE applicants
+DROP COLUMN national_id;
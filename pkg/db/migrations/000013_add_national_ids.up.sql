@@ -0,0 +1,9 @@
+ALTER TABLE applicants
+ADD COLUMN national_id VARCHAR(9);
+
+CREATE UNIQUE INDEX idx_applicants_national_id ON applicants (national_id);
+
+ALTER TABLE household_members
+ADD COLUMN national_id VARCHAR(9);
+
+CREATE UNIQUE INDEX idx_household_members_applicant_id_national_id ON household_members (applicant_id, national_id);
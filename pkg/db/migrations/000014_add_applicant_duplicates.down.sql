@@ -0,0 +1,2 @@
+DROP TABLE applicant_merges;
+DROP TABLE duplicate_candidates;
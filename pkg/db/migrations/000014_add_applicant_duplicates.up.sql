@@ -0,0 +1,28 @@
+-- Pairs of applicants suspected to be the same person, refreshed by the duplicate detection job
+CREATE TABLE duplicate_candidates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    applicant_id UUID NOT NULL REFERENCES applicants(id) ON DELETE CASCADE,
+    duplicate_id UUID NOT NULL REFERENCES applicants(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    name_similarity DOUBLE PRECISION NOT NULL,
+    same_date_of_birth BOOLEAN NOT NULL,
+    household_overlap INTEGER NOT NULL,
+    detected_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT idx_duplicate_candidates_pair UNIQUE (applicant_id, duplicate_id)
+);
+
+CREATE INDEX idx_duplicate_candidates_duplicate_id ON duplicate_candidates (duplicate_id);
+
+-- Record of applicants merged into another, keeping the merged applicant as it was
+CREATE TABLE applicant_merges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    survivor_id UUID NOT NULL,
+    merged_id UUID NOT NULL,
+    merged_applicant JSONB NOT NULL,
+    merged_by VARCHAR(255) NOT NULL,
+    applications_moved INTEGER NOT NULL,
+    household_moved INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_applicant_merges_survivor_id ON applicant_merges (survivor_id);
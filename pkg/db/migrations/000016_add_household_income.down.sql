@@ -0,0 +1,89 @@
+-- Amounts go back to whole dollars.
+-- Helpers to rescale the amounts stored in JSON, dropped at the end.
+CREATE FUNCTION scale_amount(amount jsonb, factor numeric) RETURNS jsonb AS $$
+BEGIN
+    CASE jsonb_typeof(amount)
+    WHEN 'number' THEN
+        RETURN to_jsonb(round(amount::text::numeric * factor));
+    WHEN 'array' THEN
+        RETURN (SELECT coalesce(jsonb_agg(scale_amount(element, factor) ORDER BY idx), '[]')
+                FROM jsonb_array_elements(amount) WITH ORDINALITY AS elements(element, idx));
+    ELSE
+        RETURN amount;
+    END CASE;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION scale_amounts_at(doc jsonb, paths text[], factor numeric) RETURNS jsonb AS $$
+DECLARE
+    path text;
+BEGIN
+    IF jsonb_typeof(doc) <> 'object' THEN
+        RETURN doc;
+    END IF;
+    FOREACH path IN ARRAY paths LOOP
+        IF jsonb_typeof(doc #> string_to_array(path, '.')) = 'number' THEN
+            doc := jsonb_set(doc, string_to_array(path, '.'), scale_amount(doc #> string_to_array(path, '.'), factor));
+        END IF;
+    END LOOP;
+    IF jsonb_typeof(doc->'vouchers') = 'array' THEN
+        doc := jsonb_set(doc, '{vouchers}', (
+            SELECT coalesce(jsonb_agg(scale_amounts_at(voucher, '{value}', factor) ORDER BY idx), '[]')
+            FROM jsonb_array_elements(doc->'vouchers') WITH ORDINALITY AS vouchers(voucher, idx)));
+    END IF;
+    RETURN doc;
+END;
+$$ LANGUAGE plpgsql;
+
+-- scale_income_rules rescales the values of applicant income rules anywhere
+-- in a criteria tree.
+CREATE FUNCTION scale_income_rules(criteria jsonb, factor numeric) RETURNS jsonb AS $$
+DECLARE
+    key text;
+BEGIN
+    IF jsonb_typeof(criteria) <> 'object' THEN
+        RETURN criteria;
+    END IF;
+    IF jsonb_typeof(criteria->'rules') = 'array' THEN
+        criteria := jsonb_set(criteria, '{rules}', (
+            SELECT coalesce(jsonb_agg(
+                CASE WHEN rule->>'field' = 'income' AND rule ? 'value'
+                THEN jsonb_set(rule, '{value}', scale_amount(rule->'value', factor))
+                ELSE rule END ORDER BY idx), '[]')
+            FROM jsonb_array_elements(criteria->'rules') WITH ORDINALITY AS rules(rule, idx)));
+    END IF;
+    FOREACH key IN ARRAY ARRAY['all', 'any'] LOOP
+        IF jsonb_typeof(criteria->key) = 'array' THEN
+            criteria := jsonb_set(criteria, ARRAY[key], (
+                SELECT coalesce(jsonb_agg(scale_income_rules(grp, factor) ORDER BY idx), '[]')
+                FROM jsonb_array_elements(criteria->key) WITH ORDINALITY AS groups(grp, idx)));
+        END IF;
+    END LOOP;
+    IF criteria ? 'not' THEN
+        criteria := jsonb_set(criteria, '{not}', scale_income_rules(criteria->'not', factor));
+    END IF;
+    RETURN criteria;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE applicants SET income = round(income * 0.01);
+UPDATE schemes SET budget = round(budget * 0.01);
+UPDATE schemes SET
+    criteria = scale_income_rules(criteria, 0.01),
+    benefits = scale_amounts_at(benefits, ARRAY['amount', 'cap', 'per_child.amount', 'monthly.amount'], 0.01);
+UPDATE scheme_versions SET
+    criteria = scale_income_rules(criteria, 0.01),
+    benefits = scale_amounts_at(benefits, ARRAY['amount', 'cap', 'per_child.amount', 'monthly.amount'], 0.01);
+UPDATE applications SET entitlement = scale_amounts_at(entitlement, ARRAY['one_off', 'monthly', 'total'], 0.01)
+WHERE entitlement IS NOT NULL;
+
+DROP FUNCTION scale_income_rules(jsonb, numeric);
+DROP FUNCTION scale_amounts_at(jsonb, text[], numeric);
+DROP FUNCTION scale_amount(jsonb, numeric);
+
+ALTER TABLE household_members
+DROP COLUMN income,
+DROP COLUMN income_period;
+
+ALTER TABLE applicants
+DROP COLUMN income_period;
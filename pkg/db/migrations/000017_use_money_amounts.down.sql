@@ -0,0 +1,21 @@
+UPDATE applications
+SET entitlement = entitlement
+    || jsonb_build_object('one_off', entitlement->'one_off'->'amount')
+    || jsonb_build_object('monthly', entitlement->'monthly'->'amount')
+    || jsonb_build_object('total', entitlement->'total'->'amount')
+WHERE jsonb_typeof(entitlement->'total') = 'object';
+
+ALTER TABLE schemes
+ALTER COLUMN budget DROP DEFAULT,
+ALTER COLUMN budget TYPE INTEGER USING (budget->>'amount')::integer,
+ALTER COLUMN budget SET DEFAULT 0;
+
+ALTER TABLE household_members
+ALTER COLUMN income DROP DEFAULT,
+ALTER COLUMN income TYPE INTEGER USING (income->>'amount')::integer,
+ALTER COLUMN income SET DEFAULT 0;
+
+ALTER TABLE applicants
+ALTER COLUMN income DROP DEFAULT,
+ALTER COLUMN income TYPE INTEGER USING (income->>'amount')::integer,
+ALTER COLUMN income SET DEFAULT 0;
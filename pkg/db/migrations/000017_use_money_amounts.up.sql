@@ -0,0 +1,23 @@
+ALTER TABLE applicants
+ALTER COLUMN income DROP DEFAULT,
+ALTER COLUMN income TYPE JSONB USING jsonb_build_object('amount', income, 'currency', 'SGD'),
+ALTER COLUMN income SET DEFAULT '{"amount": 0, "currency": "SGD"}';
+
+ALTER TABLE household_members
+ALTER COLUMN income DROP DEFAULT,
+ALTER COLUMN income TYPE JSONB USING jsonb_build_object('amount', income, 'currency', 'SGD'),
+ALTER COLUMN income SET DEFAULT '{"amount": 0, "currency": "SGD"}';
+
+ALTER TABLE schemes
+ALTER COLUMN budget DROP DEFAULT,
+ALTER COLUMN budget TYPE JSONB USING jsonb_build_object('amount', budget, 'currency', 'SGD'),
+ALTER COLUMN budget SET DEFAULT '{"amount": 0, "currency": "SGD"}';
+
+-- Benefits stored as bare numbers are still read as SGD, but budgets are
+-- summed from entitlement totals in SQL so those must be objects.
+UPDATE applications
+SET entitlement = entitlement
+    || jsonb_build_object('one_off', jsonb_build_object('amount', entitlement->'one_off', 'currency', 'SGD'))
+    || jsonb_build_object('monthly', jsonb_build_object('amount', entitlement->'monthly', 'currency', 'SGD'))
+    || jsonb_build_object('total', jsonb_build_object('amount', entitlement->'total', 'currency', 'SGD'))
+WHERE jsonb_typeof(entitlement->'total') = 'number';
@@ -0,0 +1,2 @@
+ALTER TABLE administrators
+DROP COLUMN role;
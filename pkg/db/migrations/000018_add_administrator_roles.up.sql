@@ -0,0 +1,5 @@
+ALTER TABLE administrators
+ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'caseworker'
+CHECK (role IN ('super_admin', 'scheme_manager', 'caseworker', 'auditor'));
+
+UPDATE administrators SET role = 'super_admin' WHERE email = 'root@root.com';
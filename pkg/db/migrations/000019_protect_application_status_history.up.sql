@@ -0,0 +1,11 @@
+-- The history outlives its application: block deletes, including cascades
+ALTER TABLE application_status_changes
+DROP CONSTRAINT application_status_changes_application_id_fkey,
+ADD CONSTRAINT application_status_changes_application_id_fkey
+    FOREIGN KEY (application_id) REFERENCES applications(id) ON DELETE RESTRICT;
+
+DROP TRIGGER application_status_changes_append_only ON application_status_changes;
+
+CREATE TRIGGER application_status_changes_append_only
+BEFORE UPDATE OR DELETE ON application_status_changes
+FOR EACH ROW EXECUTE FUNCTION reject_application_status_change_update();